@@ -1,18 +1,36 @@
-async function spawnWorker(params, timeout, progress) {
-	let worker = new Worker(new URL('./sia.worker.js', import.meta.url), { type: 'module' });
+// backend is sent to every worker before its action so that all
+// calls use the same network and walletd address. null uses the network
+// the wasm module was built for and its default SiaScan API.
+let backend = null;
+
+// spawnWorker runs an action in a new worker. The worker is terminated when
+// signal, an optional AbortSignal, is aborted.
+async function spawnWorker(params, timeout, progress, signal) {
+	let worker = new Worker(new URL('./sia.worker.js', import.meta.url), { type: 'module' }),
+		configured = backend === null;
 
 	const work = new Promise((resolve, reject) => {
 		const workerDeadline = setTimeout(() => {
 			reject(new Error('response timeout'));
 		}, timeout);
 
+		if (signal) {
+			if (signal.aborted)
+				reject(new Error('cancelled'));
+
+			signal.addEventListener('abort', () => {
+				clearTimeout(workerDeadline);
+				reject(new Error('cancelled'));
+			}, { once: true });
+		}
+
 		worker.onmessage = (e) => {
 			const data = e.data;
 
 			clearTimeout(workerDeadline);
 
 			if (data === 'ready') {
-				worker.postMessage(params);
+				worker.postMessage(configured ? params : ['configure', backend.network, backend.address, backend.password]);
 				return;
 			}
 
@@ -32,6 +50,12 @@ async function spawnWorker(params, timeout, progress) {
 				progress(data[1]);
 				return;
 			case null:
+				if (!configured) {
+					configured = true;
+					worker.postMessage(params);
+					return;
+				}
+
 				resolve(data[1]);
 				return;
 			default:
@@ -48,18 +72,67 @@ async function spawnWorker(params, timeout, progress) {
 	return work;
 }
 
+export async function configure({ network = '', address = '', password = '' } = {}) {
+	if (!network && !address) {
+		backend = null;
+		return;
+	}
+
+	// validate the settings in a worker before using them for other calls
+	const resp = await spawnWorker(['configure', network, address, password], 15000);
+	backend = { network: resp.network, address: resp.address, password };
+}
+
 export function generateSeed(type) {
 	return spawnWorker(['generateSeed', type], 15000);
 }
 
-export function generateAddresses(seed, i, n) {
-	return spawnWorker(['generateAddresses', seed, i, n], 15000);
+// validatePhrase checks a recovery phrase. It returns the detected format,
+// the unknown words with suggested corrections and whether the checksum
+// failed.
+export function validatePhrase(phrase) {
+	return spawnWorker(['validatePhrase', phrase], 15000);
 }
 
+// repairPhrase returns the phrases with a valid checksum that differ from a
+// damaged phrase by one missing word, one wrong word or one swapped pair of
+// adjacent words. If confirmAddresses is set, that many keys (at most 100) of
+// each candidate are checked on chain and used candidates are returned first.
+// The search is cancelled when signal is aborted.
+export async function repairPhrase(phrase, confirmAddresses = 0, progress, signal) {
+	const { candidates } = await spawnWorker(['repairPhrase', phrase, confirmAddresses], 600000, progress, signal);
+	return candidates;
+}
+
+// convertPhrase converts a recovery phrase to the siad phrase of the same
+// seed in language (english, german or japanese). A 12-word phrase cannot be
+// derived from an arbitrary seed, so type 'walrus' is only accepted for a
+// phrase that is already a 12-word phrase. The addresses of both phrases are
+// checked to match before the result is returned.
+export function convertPhrase(phrase, type = 'sia', language = 'english') {
+	return spawnWorker(['convertPhrase', phrase, type, language], 15000);
+}
+
+// generateAddresses derives n addresses starting at index i. type is
+// unlock_conditions for v1 addresses or public_key for v2 policy addresses.
+export function generateAddresses(seed, i, n, type = 'unlock_conditions') {
+	return spawnWorker(['generateAddresses', seed, i, n, type || 'unlock_conditions'], 15000);
+}
+
+// getTransactions returns the balance and transactions of the addresses.
+// Wallet address objects are also scanned at the other address type of
+// their key.
 export function getTransactions(addresses) {
-	return spawnWorker(['getTransactions', addresses], 30000);
+	return spawnWorker(['getTransactions', addresses.map(a => typeof a === 'string' ? a : JSON.stringify(a))], 30000);
 }
 
+// signTransaction signs a v1 transaction. indexes is either an array with the
+// key index of each signature, in order, or signing options of the form
+// { addresses: { [address]: index } } or { scan_start, scan_count }. With
+// options, each input is matched to its key by parent address and the result
+// is { transaction, unsigned_inputs } listing the inputs that could not be
+// signed. Each signature must cover the whole transaction; its
+// public_key_index, timelock and covered signatures are honored.
 export function signTransaction(seed, txn, indexes) {
 	return spawnWorker(['signTransaction', seed, JSON.stringify(txn), indexes], 15000);
 }
@@ -68,10 +141,59 @@ export function encodeTransaction(txn) {
 	return spawnWorker(['encodeTransaction', JSON.stringify(txn)], 15000);
 }
 
+// encodeV2Transaction returns the sighash encoding of a v2 transaction's
+// semantics. It omits parents and signatures, so decodeV2Transaction cannot
+// decode it; use encodeV2TransactionFull for that.
 export function encodeV2Transaction(txn) {
 	return spawnWorker(['encodeV2Transaction', JSON.stringify(txn)], 15000);
 }
 
+// encodeV2TransactionFull returns the full encoding of a v2 transaction,
+// including parents and signatures, which decodeV2Transaction accepts
+export function encodeV2TransactionFull(txn) {
+	return spawnWorker(['encodeV2TransactionFull', JSON.stringify(txn)], 15000);
+}
+
+// req is { addresses, recipients, change_address, fee_rate, strategy }.
+// addresses are the wallet's addresses as returned by generateAddresses.
+// strategy is one of largest_first (default), smallest_first, exact_match or
+// privacy. The result contains the unsigned transaction and the
+// signing_indices to pass to v2SignTransaction.
+export function buildV2Transaction(req) {
+	return spawnWorker(['buildV2Transaction', JSON.stringify(req)], 30000);
+}
+
+// sendSiacoins builds and signs a transaction in the format valid at the
+// current height: v1 before the v2 allow height and v2 after it. req is the
+// same as buildV2Transaction with an optional format ("v1" or "v2") to pick
+// the format while both are valid, between the allow and require heights.
+// The result is { format, basis, transaction, v2_transaction, fee, change }
+// where only the transaction matching format is set.
+export function sendSiacoins(seed, req) {
+	return spawnWorker(['sendSiacoins', seed, JSON.stringify(req)], 30000);
+}
+
+// req is { addresses, target, max_inputs, fee_rate }. target must be one of
+// the wallet's addresses. The result contains the unsigned transactions,
+// each with its own signing_indices, the outputs skipped because they are
+// worth less than the fee to spend them, the remaining spendable outputs
+// that could not be put in a transaction, and the total fee.
+export function planConsolidation(req) {
+	return spawnWorker(['planConsolidation', JSON.stringify(req)], 60000);
+}
+
+// buf is a Uint8Array or a string in encoding, 'hex' or 'base64'
+export function decodeTransaction(buf, encoding = 'hex') {
+	return spawnWorker(['decodeTransaction', buf, encoding], 15000);
+}
+
+// buf is a Uint8Array or a string in encoding, 'hex' or 'base64'. It must be
+// the full v2 transaction encoding returned by encodeV2TransactionFull;
+// the sighash encoding returned by encodeV2Transaction cannot be decoded.
+export function decodeV2Transaction(buf, encoding = 'hex') {
+	return spawnWorker(['decodeV2Transaction', buf, encoding], 15000);
+}
+
 export function encodeUnlockHash(unlockconditions) {
 	return spawnWorker(['encodeUnlockHash', JSON.stringify(unlockconditions)], 15000);
 }
@@ -84,7 +206,190 @@ export function v2InputSigHash(txn) {
 	return spawnWorker(['v2InputSigHash', JSON.stringify(txn)], 15000);
 }
 
+// v2SignTransaction signs each input with the key at the matching index,
+// siacoin inputs first. The spend policy of each input is set from its key,
+// so callers do not need to fill it in. Signing fails, naming the input, if
+// an input's parent address does not belong to its key. indexes may also be
+// signing options as for signTransaction, in which case the result is
+// { transaction, unsigned_inputs }.
 export function v2SignTransaction(seed, txn, indexes) {
 	const str = JSON.stringify(txn);
 	return spawnWorker(['v2SignTransaction', seed, str, indexes], 15000);
 }
+
+// state is either { network, state } with a serialized consensus state or
+// { network, index, hardforks } with a chain index. Signing with a state
+// does not contact the backend.
+export function signTransactionWithState(seed, txn, indexes, state) {
+	return spawnWorker(['signTransactionWithState', seed, JSON.stringify(txn), indexes, JSON.stringify(state)], 15000);
+}
+
+// newMultisig returns the { address, policy, required, public_keys } of an
+// address that requires signatures from required of the public keys. Every
+// cosigner must pass the keys in the same order.
+export function newMultisig(required, publicKeys) {
+	return spawnWorker(['newMultisig', required, JSON.stringify(publicKeys)], 15000);
+}
+
+export function exportPublicKey(seed, index) {
+	return spawnWorker(['exportPublicKey', seed, index], 15000);
+}
+
+// addMultisigSignature adds the signature of the key at index to every
+// multisig input of txn, keeping the signatures of other cosigners.
+export function addMultisigSignature(seed, txn, index) {
+	return spawnWorker(['addMultisigSignature', seed, JSON.stringify(txn), index], 15000);
+}
+
+export function mergeMultisigSignatures(txns) {
+	return spawnWorker(['mergeMultisigSignatures', JSON.stringify(txns)], 15000);
+}
+
+// finalizeMultisig converts the collected signatures into the form accepted
+// by the network. It must be called once enough cosigners have signed.
+export function finalizeMultisig(txn) {
+	return spawnWorker(['finalizeMultisig', JSON.stringify(txn)], 15000);
+}
+
+// newVault derives an address that the key at index can only spend at or
+// after unlockHeight and after the unix timestamp unlockTime. Pass 0 to skip
+// either lock.
+export function newVault(seed, index, unlockHeight, unlockTime) {
+	return spawnWorker(['newVault', seed, index, unlockHeight, unlockTime], 15000);
+}
+
+// findVaults returns the used vaults with the given lock for the n keys
+// starting at start. n must be between 1 and 10000.
+export function findVaults(seed, unlockHeight, unlockTime, start = 0, n = 100) {
+	return spawnWorker(['findVaults', seed, unlockHeight, unlockTime, start, n], 60000);
+}
+
+// getVaultOutputs returns the unspent outputs of the vaults and whether each
+// is unlocked.
+export function getVaultOutputs(vaults) {
+	return spawnWorker(['getVaultOutputs', JSON.stringify(vaults)], 30000);
+}
+
+// sweepVaults returns { basis, transaction } sending every unlocked vault
+// output to destination. feeRate is in hastings per byte.
+export function sweepVaults(seed, vaults, destination, feeRate) {
+	return spawnWorker(['sweepVaults', seed, JSON.stringify(vaults), destination, feeRate], 30000);
+}
+
+// newHTLC returns a hash time locked address that claimKey can spend with the
+// preimage of hash or that refundKey can spend once the chain reaches the
+// timeout height. Keys are exported with exportPublicKey.
+export function newHTLC(claimKey, refundKey, hash, timeout) {
+	return spawnWorker(['newHTLC', claimKey, refundKey, hash, timeout], 15000);
+}
+
+// newPreimage returns a random { preimage, hash } for newHTLC.
+export function newPreimage() {
+	return spawnWorker(['newPreimage'], 15000);
+}
+
+// fundHTLC builds an unsigned transaction sending value to the HTLC. req is
+// the same as buildV2Transaction without recipients.
+export function fundHTLC(req, htlc, value) {
+	return buildV2Transaction({ ...req, recipients: [{ address: htlc.address, value }] });
+}
+
+// claimHTLC returns { basis, transaction } sending the HTLC's outputs to
+// destination. The key at index must be the claim key. Claiming reveals the
+// preimage to the counterparty.
+export function claimHTLC(seed, index, htlc, preimage, destination, feeRate) {
+	return spawnWorker(['claimHTLC', seed, index, JSON.stringify(htlc), destination, feeRate, preimage], 30000);
+}
+
+// refundHTLC returns { basis, transaction } sending the HTLC's outputs to
+// destination. The key at index must be the refund key and the timeout height
+// must have been reached.
+export function refundHTLC(seed, index, htlc, destination, feeRate) {
+	return spawnWorker(['refundHTLC', seed, index, JSON.stringify(htlc), destination, feeRate], 30000);
+}
+
+// findHTLCPreimage returns the preimage revealed by a confirmed claim of the
+// HTLC or null if it has not been claimed.
+export function findHTLCPreimage(htlc) {
+	return spawnWorker(['findHTLCPreimage', JSON.stringify(htlc)], 30000);
+}
+
+// signMessage signs a string or Uint8Array with the key at index to prove
+// ownership of its address. The signature is a string of the form
+// "sia-msg-v1:ed25519:<public key>:<signature>".
+export function signMessage(seed, index, message) {
+	return spawnWorker(['signMessage', seed, index, message], 15000);
+}
+
+// verifyMessage checks a signature returned by signMessage. signer is an
+// address or its unlock conditions. The result is { address, public_key }.
+export function verifyMessage(signer, message, signature) {
+	if (typeof signer !== 'string')
+		signer = JSON.stringify(signer);
+
+	return spawnWorker(['verifyMessage', signer, message, signature], 15000);
+}
+
+// proveReserves returns a proof that the wallet controls its unspent siacoin
+// and siafund outputs at the current tip. Every address owning an output
+// signs the challenge. addresses are the wallet's addresses as returned by
+// generateAddresses.
+export function proveReserves(seed, addresses, challenge) {
+	return spawnWorker(['proveReserves', seed, JSON.stringify(addresses), challenge], 60000);
+}
+
+// verifyReserveProof checks a proof returned by proveReserves against the
+// chain state at the proof's index. The result is { index, siacoins,
+// siafunds }.
+export function verifyReserveProof(proof, challenge) {
+	return spawnWorker(['verifyReserveProof', JSON.stringify(proof), challenge], 60000);
+}
+
+// Partial transactions are passed between signers as base64 strings. They
+// carry the transaction, its parent elements and spend policies, the key
+// index hints of each input and the signatures collected so far.
+
+// createPartialTransaction returns a partial transaction for an unsigned
+// transaction returned by buildV2Transaction.
+export function createPartialTransaction(unsigned) {
+	return spawnWorker(['createPartialTransaction', JSON.stringify(unsigned)], 15000);
+}
+
+// inspectPartialTransaction returns the inputs, outputs and signing state of
+// a partial transaction. Signatures that do not verify are listed in each
+// input's invalid keys and do not count towards completing it.
+export function inspectPartialTransaction(partial) {
+	return spawnWorker(['inspectPartialTransaction', partial], 15000);
+}
+
+// signPartialTransaction signs every input that can be signed by the keys
+// at the inputs' index hints or at any of indices.
+export function signPartialTransaction(seed, partial, indices = []) {
+	return spawnWorker(['signPartialTransaction', seed, partial, indices], 15000);
+}
+
+// combinePartialTransactions merges the signatures of partial transactions
+// for the same transaction and basis. A partial with an invalid signature is
+// refused.
+export function combinePartialTransactions(partials) {
+	return spawnWorker(['combinePartialTransactions', partials], 15000);
+}
+
+// finalizePartialTransaction returns the signed transaction once every input
+// has enough signatures.
+export function finalizePartialTransaction(partial) {
+	return spawnWorker(['finalizePartialTransaction', partial], 15000);
+}
+
+// validateV2Transaction checks a signed v2 transaction against the current
+// tip state. minFeeRate is the minimum fee per byte in hastings; pass an
+// empty string to skip the fee check. The result is { valid, id, weight, fee,
+// min_fee, issues } where each issue has a code, message and, if it applies
+// to an input, the siacoin_input or siafund_input index.
+export function validateV2Transaction(txn, minFeeRate) {
+	return spawnWorker(['validateV2Transaction', JSON.stringify(txn), minFeeRate || ''], 30000);
+}
+
+export function v2SignTransactionWithState(seed, txn, indexes, state) {
+	return spawnWorker(['v2SignTransactionWithState', seed, JSON.stringify(txn), indexes, JSON.stringify(state)], 15000);
+}
@@ -5,6 +5,7 @@ export default class Wallet {
 		this.id = data.id;
 		this.seed = data.seed;
 		this.type = data.type;
+		this.address_type = data.address_type || 'unlock_conditions';
 		this.title = data.title;
 		this.scanning = data.scanning;
 		this.salt = data.salt;
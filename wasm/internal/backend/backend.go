@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/api"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// A Backend provides the chain data needed by the wallet. It is satisfied by
+// *api.Client and by Memory.
+type Backend interface {
+	// ConsensusTip returns the current tip index.
+	ConsensusTip() (types.ChainIndex, error)
+	// ConsensusTipState returns the current tip state.
+	ConsensusTipState() (consensus.State, error)
+
+	// TPoolEvents returns all unconfirmed events in the transaction pool.
+	TPoolEvents() ([]wallet.Event, error)
+	// BatchAddressEvents returns the events for a batch of addresses.
+	BatchAddressEvents(addresses []types.Address, offset, limit int) ([]wallet.Event, error)
+	// BatchAddressBalance returns the balance of a batch of addresses.
+	BatchAddressBalance(addresses []types.Address) (api.BalanceResponse, error)
+	// BatchAddressSiacoinOutputs returns the unspent siacoin outputs for a
+	// batch of addresses.
+	BatchAddressSiacoinOutputs(addresses []types.Address, offset, limit int) ([]wallet.UnspentSiacoinElement, types.ChainIndex, error)
+	// BatchAddressSiafundOutputs returns the unspent siafund outputs for a
+	// batch of addresses.
+	BatchAddressSiafundOutputs(addresses []types.Address, offset, limit int) ([]wallet.UnspentSiafundElement, types.ChainIndex, error)
+	// CheckAddresses returns true if any of the addresses have been seen on
+	// chain.
+	CheckAddresses(addresses []types.Address) (bool, error)
+}
+
+var _ Backend = (*api.Client)(nil)
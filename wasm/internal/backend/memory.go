@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"slices"
+	"sync"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/api"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// Memory is an in-memory Backend that can be seeded with chain data. It is
+// primarily intended for tests.
+type Memory struct {
+	mu          sync.Mutex
+	state       consensus.State
+	events      []wallet.Event
+	unconfirmed []wallet.Event
+	siacoins    []types.SiacoinElement
+	siafunds    []types.SiafundElement
+	used        map[types.Address]bool
+}
+
+var _ Backend = (*Memory)(nil)
+
+// paginate returns the page of s starting at offset with at most limit
+// elements.
+func paginate[T any](s []T, offset, limit int) []T {
+	if offset >= len(s) {
+		return nil
+	}
+	return s[offset:min(offset+limit, len(s))]
+}
+
+// addressSet returns a set containing the addresses.
+func addressSet(addresses []types.Address) map[types.Address]bool {
+	set := make(map[types.Address]bool, len(addresses))
+	for _, addr := range addresses {
+		set[addr] = true
+	}
+	return set
+}
+
+// SetState sets the current tip state.
+func (m *Memory) SetState(cs consensus.State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = cs
+}
+
+// AddEvents adds confirmed events. Each event is returned for the addresses in
+// its Relevant field.
+func (m *Memory) AddEvents(events ...wallet.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range events {
+		for _, addr := range event.Relevant {
+			m.used[addr] = true
+		}
+	}
+	m.events = append(m.events, events...)
+	// events are returned newest first
+	slices.SortStableFunc(m.events, func(a, b wallet.Event) int {
+		switch {
+		case a.Index.Height > b.Index.Height:
+			return -1
+		case a.Index.Height < b.Index.Height:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// AddUnconfirmedEvents adds events to the transaction pool.
+func (m *Memory) AddUnconfirmedEvents(events ...wallet.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unconfirmed = append(m.unconfirmed, events...)
+}
+
+// AddSiacoinElements adds unspent siacoin elements.
+func (m *Memory) AddSiacoinElements(sces ...types.SiacoinElement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sce := range sces {
+		m.used[sce.SiacoinOutput.Address] = true
+	}
+	m.siacoins = append(m.siacoins, sces...)
+}
+
+// AddSiafundElements adds unspent siafund elements.
+func (m *Memory) AddSiafundElements(sfes ...types.SiafundElement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sfe := range sfes {
+		m.used[sfe.SiafundOutput.Address] = true
+	}
+	m.siafunds = append(m.siafunds, sfes...)
+}
+
+// SpendSiacoinElement removes an unspent siacoin element. The address remains
+// marked as used.
+func (m *Memory) SpendSiacoinElement(id types.SiacoinOutputID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.siacoins = slices.DeleteFunc(m.siacoins, func(sce types.SiacoinElement) bool {
+		return sce.ID == id
+	})
+}
+
+// SpendSiafundElement removes an unspent siafund element. The address remains
+// marked as used.
+func (m *Memory) SpendSiafundElement(id types.SiafundOutputID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.siafunds = slices.DeleteFunc(m.siafunds, func(sfe types.SiafundElement) bool {
+		return sfe.ID == id
+	})
+}
+
+// ConsensusTip implements Backend.
+func (m *Memory) ConsensusTip() (types.ChainIndex, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.Index, nil
+}
+
+// ConsensusTipState implements Backend.
+func (m *Memory) ConsensusTipState() (consensus.State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, nil
+}
+
+// TPoolEvents implements Backend.
+func (m *Memory) TPoolEvents() ([]wallet.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.unconfirmed), nil
+}
+
+// BatchAddressEvents implements Backend.
+func (m *Memory) BatchAddressEvents(addresses []types.Address, offset, limit int) ([]wallet.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	relevant := addressSet(addresses)
+	var events []wallet.Event
+	for _, event := range m.events {
+		if slices.ContainsFunc(event.Relevant, func(addr types.Address) bool { return relevant[addr] }) {
+			if event.Index.Height <= m.state.Index.Height {
+				event.Confirmations = m.state.Index.Height - event.Index.Height + 1
+			}
+			events = append(events, event)
+		}
+	}
+	return paginate(events, offset, limit), nil
+}
+
+// BatchAddressBalance implements Backend.
+func (m *Memory) BatchAddressBalance(addresses []types.Address) (resp api.BalanceResponse, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	relevant := addressSet(addresses)
+	for _, sce := range m.siacoins {
+		switch {
+		case !relevant[sce.SiacoinOutput.Address]:
+		case sce.MaturityHeight > m.state.Index.Height:
+			resp.ImmatureSiacoins = resp.ImmatureSiacoins.Add(sce.SiacoinOutput.Value)
+		default:
+			resp.Siacoins = resp.Siacoins.Add(sce.SiacoinOutput.Value)
+		}
+	}
+	for _, sfe := range m.siafunds {
+		if relevant[sfe.SiafundOutput.Address] {
+			resp.Siafunds += sfe.SiafundOutput.Value
+		}
+	}
+	return resp, nil
+}
+
+// BatchAddressSiacoinOutputs implements Backend.
+func (m *Memory) BatchAddressSiacoinOutputs(addresses []types.Address, offset, limit int) ([]wallet.UnspentSiacoinElement, types.ChainIndex, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	relevant := addressSet(addresses)
+	var utxos []wallet.UnspentSiacoinElement
+	for _, sce := range m.siacoins {
+		if relevant[sce.SiacoinOutput.Address] {
+			utxos = append(utxos, wallet.UnspentSiacoinElement{SiacoinElement: sce.Copy()})
+		}
+	}
+	return paginate(utxos, offset, limit), m.state.Index, nil
+}
+
+// BatchAddressSiafundOutputs implements Backend.
+func (m *Memory) BatchAddressSiafundOutputs(addresses []types.Address, offset, limit int) ([]wallet.UnspentSiafundElement, types.ChainIndex, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	relevant := addressSet(addresses)
+	var utxos []wallet.UnspentSiafundElement
+	for _, sfe := range m.siafunds {
+		if relevant[sfe.SiafundOutput.Address] {
+			utxos = append(utxos, wallet.UnspentSiafundElement{SiafundElement: sfe.Copy()})
+		}
+	}
+	return paginate(utxos, offset, limit), m.state.Index, nil
+}
+
+// CheckAddresses implements Backend.
+func (m *Memory) CheckAddresses(addresses []types.Address) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, addr := range addresses {
+		if m.used[addr] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewMemory returns an empty in-memory backend with the given tip state.
+func NewMemory(cs consensus.State) *Memory {
+	return &Memory{
+		state: cs,
+		used:  make(map[types.Address]bool),
+	}
+}
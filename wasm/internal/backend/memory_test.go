@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+func TestMemoryOutputs(t *testing.T) {
+	n, _ := chain.Mainnet()
+	cs := n.GenesisState()
+	cs.Index.Height = 100
+
+	m := NewMemory(cs)
+
+	addr := frand.Entropy256()
+	other := frand.Entropy256()
+
+	var sces []types.SiacoinElement
+	for i := range 250 {
+		sces = append(sces, types.SiacoinElement{
+			ID: frand.Entropy256(),
+			SiacoinOutput: types.SiacoinOutput{
+				Address: addr,
+				Value:   types.Siacoins(1),
+			},
+			MaturityHeight: uint64(i), // half of the outputs are immature
+		})
+	}
+	m.AddSiacoinElements(sces...)
+	m.AddSiafundElements(types.SiafundElement{
+		ID: frand.Entropy256(),
+		SiafundOutput: types.SiafundOutput{
+			Address: addr,
+			Value:   10,
+		},
+	})
+
+	if ok, err := m.CheckAddresses([]types.Address{other}); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected unused address")
+	} else if ok, err := m.CheckAddresses([]types.Address{other, addr}); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected used address")
+	}
+
+	balance, err := m.BatchAddressBalance([]types.Address{addr})
+	if err != nil {
+		t.Fatal(err)
+	} else if !balance.Siacoins.Equals(types.Siacoins(101)) {
+		t.Fatalf("expected 101 SC, got %v", balance.Siacoins)
+	} else if !balance.ImmatureSiacoins.Equals(types.Siacoins(149)) {
+		t.Fatalf("expected 149 SC immature, got %v", balance.ImmatureSiacoins)
+	} else if balance.Siafunds != 10 {
+		t.Fatalf("expected 10 SF, got %v", balance.Siafunds)
+	}
+
+	var utxos []wallet.UnspentSiacoinElement
+	for offset := 0; ; offset += 100 {
+		page, basis, err := m.BatchAddressSiacoinOutputs([]types.Address{addr}, offset, 100)
+		if err != nil {
+			t.Fatal(err)
+		} else if basis != cs.Index {
+			t.Fatalf("expected basis %v, got %v", cs.Index, basis)
+		}
+		utxos = append(utxos, page...)
+		if len(page) < 100 {
+			break
+		}
+	}
+	if len(utxos) != len(sces) {
+		t.Fatalf("expected %d outputs, got %d", len(sces), len(utxos))
+	}
+
+	m.SpendSiacoinElement(sces[0].ID)
+	if utxos, _, err := m.BatchAddressSiacoinOutputs([]types.Address{addr}, 0, 1000); err != nil {
+		t.Fatal(err)
+	} else if len(utxos) != len(sces)-1 {
+		t.Fatalf("expected %d outputs, got %d", len(sces)-1, len(utxos))
+	}
+}
+
+func TestMemoryEvents(t *testing.T) {
+	n, _ := chain.Mainnet()
+	cs := n.GenesisState()
+	cs.Index.Height = 100
+
+	m := NewMemory(cs)
+
+	addr := frand.Entropy256()
+	for i := range 10 {
+		m.AddEvents(wallet.Event{
+			ID:       frand.Entropy256(),
+			Index:    types.ChainIndex{Height: uint64(i + 1)},
+			Relevant: []types.Address{addr},
+		})
+	}
+	m.AddEvents(wallet.Event{
+		ID:       frand.Entropy256(),
+		Index:    types.ChainIndex{Height: 50},
+		Relevant: []types.Address{frand.Entropy256()},
+	})
+
+	events, err := m.BatchAddressEvents([]types.Address{addr}, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(events))
+	}
+
+	for i, event := range events {
+		// events should be newest first
+		if expected := uint64(10 - i); event.Index.Height != expected {
+			t.Fatalf("expected event %d at height %d, got %d", i, expected, event.Index.Height)
+		} else if expected := cs.Index.Height - event.Index.Height + 1; event.Confirmations != expected {
+			t.Fatalf("expected %d confirmations, got %d", expected, event.Confirmations)
+		}
+	}
+
+	if events, err := m.BatchAddressEvents([]types.Address{addr}, 5, 100); err != nil {
+		t.Fatal(err)
+	} else if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(events))
+	}
+}
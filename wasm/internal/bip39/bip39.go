@@ -0,0 +1,24 @@
+// Package bip39 contains the english word list of 12-word BIP39 recovery
+// phrases.
+package bip39
+
+import "slices"
+
+var wordIndex = func() map[string]int {
+	m := make(map[string]int, len(english))
+	for i, w := range english {
+		m[w] = i
+	}
+	return m
+}()
+
+// Words returns the english word list.
+func Words() []string {
+	return slices.Clone(english)
+}
+
+// WordIndex returns the index of the word in the word list.
+func WordIndex(word string) (int, bool) {
+	i, ok := wordIndex[word]
+	return i, ok
+}
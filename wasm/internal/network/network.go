@@ -0,0 +1,99 @@
+package network
+
+import (
+	"fmt"
+	"time"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+)
+
+// Names of the supported networks.
+const (
+	Mainnet = "mainnet"
+	Zen     = "zen"
+	Devnet  = "devnet"
+)
+
+// Parameters are the consensus parameters and default backend of a Sia
+// network.
+type Parameters struct {
+	Name           string
+	Network        *consensus.Network
+	Genesis        types.Block
+	BackendAddress string
+}
+
+// devnet returns the parameters of a local development network. It is a
+// modified version of Zen with fast blocks and early hardforks, matching the
+// network used by coreutils' test utilities. Like those, it keeps Zen's
+// network name, so backends must be matched with CheckNetwork.
+func devnet() (*consensus.Network, types.Block) {
+	n, genesis := chain.TestnetZen()
+	n.InitialTarget = types.BlockID{0xFF}
+	n.BlockInterval = time.Second
+	n.MaturityDelay = 5
+
+	n.HardforkDevAddr.Height = 1
+	n.HardforkTax.Height = 1
+	n.HardforkStorageProof.Height = 1
+	n.HardforkOak.Height = 1
+	n.HardforkASIC.Height = 1
+	n.HardforkFoundation.Height = 1
+	n.HardforkV2.AllowHeight = 200
+	n.HardforkV2.RequireHeight = 250
+	n.HardforkV2.FinalCutHeight = 300
+	return n, genesis
+}
+
+// Lookup returns the parameters of the named network. An empty name returns
+// the network selected at build time.
+func Lookup(name string) (Parameters, error) {
+	var p Parameters
+	switch name {
+	case "":
+		return Lookup(defaultNetwork)
+	case Mainnet:
+		p.Network, p.Genesis = chain.Mainnet()
+		p.BackendAddress = "https://api.siascan.com/wallet"
+	case Zen:
+		p.Network, p.Genesis = chain.TestnetZen()
+		p.BackendAddress = "https://api.siascan.com/zen/wallet"
+	case Devnet:
+		p.Network, p.Genesis = devnet()
+		p.BackendAddress = "http://localhost:9980/api"
+	default:
+		return Parameters{}, fmt.Errorf("unknown network %q", name)
+	}
+	p.Name = name
+	return p, nil
+}
+
+// CheckNetwork returns an error if n, a backend's network, is not p's
+// network. Devnet reports the same name and genesis as Zen, so the hardfork
+// heights and block parameters are compared as well.
+func (p Parameters) CheckNetwork(n *consensus.Network) error {
+	switch {
+	case n.Name != p.Network.Name:
+		return fmt.Errorf("backend is on network %q, expected %q", n.Name, p.Name)
+	case n.InitialTarget != p.Network.InitialTarget,
+		n.MaturityDelay != p.Network.MaturityDelay,
+		n.HardforkOak.Height != p.Network.HardforkOak.Height,
+		n.HardforkASIC.Height != p.Network.HardforkASIC.Height,
+		n.HardforkFoundation.Height != p.Network.HardforkFoundation.Height,
+		n.HardforkV2 != p.Network.HardforkV2:
+		return fmt.Errorf("backend is on network %q with different consensus parameters than %q", n.Name, p.Name)
+	}
+	return nil
+}
+
+// Default returns the parameters of the network selected at build time. The
+// "testnet" build tag selects Zen, otherwise mainnet is used.
+func Default() Parameters {
+	p, err := Lookup(defaultNetwork)
+	if err != nil {
+		panic(err) // should never happen
+	}
+	return p
+}
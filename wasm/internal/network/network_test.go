@@ -0,0 +1,92 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{Mainnet, Zen, Devnet} {
+		p, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		} else if p.Name != name || (p.Network.Name != name && name != Devnet) {
+			t.Fatalf("expected network %q, got %q (%q)", name, p.Name, p.Network.Name)
+		} else if p.BackendAddress == "" {
+			t.Fatalf("%s: missing backend address", name)
+		}
+
+		hf := p.Network.HardforkV2
+		if hf.AllowHeight == 0 || hf.AllowHeight >= hf.RequireHeight || hf.RequireHeight > hf.FinalCutHeight {
+			t.Fatalf("%s: unexpected v2 hardfork heights %+v", name, hf)
+		}
+	}
+
+	if p, err := Lookup(""); err != nil {
+		t.Fatal(err)
+	} else if p.Name != defaultNetwork || Default().Name != defaultNetwork {
+		t.Fatalf("expected default network %q, got %q", defaultNetwork, p.Name)
+	}
+
+	if _, err := Lookup("foo"); err == nil {
+		t.Fatal("expected unknown network error")
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	// coreutils' test network, as run by a local walletd, is a modified Zen
+	// that keeps Zen's name
+	n, _ := chain.TestnetZen()
+	n.InitialTarget = types.BlockID{0xFF}
+	n.BlockInterval = time.Second
+	n.MaturityDelay = 5
+	n.HardforkDevAddr.Height = 1
+	n.HardforkTax.Height = 1
+	n.HardforkStorageProof.Height = 1
+	n.HardforkOak.Height = 1
+	n.HardforkASIC.Height = 1
+	n.HardforkFoundation.Height = 1
+	n.HardforkV2.AllowHeight = 200
+	n.HardforkV2.RequireHeight = 250
+	n.HardforkV2.FinalCutHeight = 300
+	tip := n.GenesisState()
+
+	// walletd's client receives the tip state and its network as JSON
+	buf, err := json.Marshal(tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cs consensus.State
+	if err := json.Unmarshal(buf, &cs); err != nil {
+		t.Fatal(err)
+	} else if buf, err = json.Marshal(tip.Network); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(buf, &cs.Network); err != nil {
+		t.Fatal(err)
+	} else if cs.Network.Name != Zen {
+		t.Fatalf("expected the test network to be named %q, got %q", Zen, cs.Network.Name)
+	}
+
+	devnet, err := Lookup(Devnet)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := devnet.CheckNetwork(cs.Network); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{Mainnet, Zen} {
+		p, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := p.CheckNetwork(cs.Network); err == nil {
+			t.Fatalf("expected %s to reject the devnet backend", name)
+		} else if err := p.CheckNetwork(p.Network); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+type (
+	// Hardforks overrides the hardfork heights of a network. Only the
+	// heights that affect signature hashes can be overridden.
+	Hardforks struct {
+		ASICHeight       *uint64 `json:"asicHeight,omitempty"`
+		FoundationHeight *uint64 `json:"foundationHeight,omitempty"`
+		V2AllowHeight    *uint64 `json:"v2AllowHeight,omitempty"`
+		V2RequireHeight  *uint64 `json:"v2RequireHeight,omitempty"`
+	}
+
+	// A SigningState describes the consensus state used to sign transactions
+	// without contacting a backend. Either State, a serialized consensus
+	// state, or Index must be set.
+	SigningState struct {
+		Network   string            `json:"network"`
+		State     *consensus.State  `json:"state,omitempty"`
+		Index     *types.ChainIndex `json:"index,omitempty"`
+		Hardforks *Hardforks        `json:"hardforks,omitempty"`
+	}
+)
+
+// ConsensusState returns the consensus state described by ss. If ss does not
+// specify a network, defaultNetwork is used.
+func (ss SigningState) ConsensusState(defaultNetwork string) (consensus.State, error) {
+	name := ss.Network
+	if name == "" {
+		name = defaultNetwork
+	}
+	p, err := Lookup(name)
+	if err != nil {
+		return consensus.State{}, err
+	}
+
+	if hf := ss.Hardforks; hf != nil {
+		if hf.ASICHeight != nil {
+			p.Network.HardforkASIC.Height = *hf.ASICHeight
+		}
+		if hf.FoundationHeight != nil {
+			p.Network.HardforkFoundation.Height = *hf.FoundationHeight
+		}
+		if hf.V2AllowHeight != nil {
+			p.Network.HardforkV2.AllowHeight = *hf.V2AllowHeight
+		}
+		if hf.V2RequireHeight != nil {
+			p.Network.HardforkV2.RequireHeight = *hf.V2RequireHeight
+		}
+		if p.Network.HardforkV2.AllowHeight > p.Network.HardforkV2.RequireHeight {
+			return consensus.State{}, fmt.Errorf("v2 allow height %d is after require height %d", p.Network.HardforkV2.AllowHeight, p.Network.HardforkV2.RequireHeight)
+		}
+	}
+
+	var cs consensus.State
+	switch {
+	case ss.State != nil && ss.Index != nil:
+		return consensus.State{}, errors.New("only one of state or index can be specified")
+	case ss.State != nil:
+		cs = *ss.State
+	case ss.Index != nil:
+		cs = p.Network.GenesisState()
+		cs.Index = *ss.Index
+	default:
+		return consensus.State{}, errors.New("either state or index must be specified")
+	}
+	cs.Network = p.Network
+	return cs, nil
+}
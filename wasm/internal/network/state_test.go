@@ -0,0 +1,77 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestSigningState(t *testing.T) {
+	p, err := Lookup(Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	online := p.Network.GenesisState()
+	online.Index = types.ChainIndex{Height: 400000, ID: frand.Entropy256()}
+	online.SiafundTaxRevenue = types.Siacoins(100)
+
+	txn := types.Transaction{
+		SiacoinInputs:  []types.SiacoinInput{{ParentID: frand.Entropy256()}},
+		SiacoinOutputs: []types.SiacoinOutput{{Address: frand.Entropy256(), Value: types.Siacoins(1)}},
+	}
+	parentID := types.Hash256(txn.SiacoinInputs[0].ParentID)
+
+	// a serialized consensus state should produce the same sig hash
+	buf, err := json.Marshal(online)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ss SigningState
+	if err := json.Unmarshal([]byte(`{"network":"mainnet","state":`+string(buf)+`}`), &ss); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := ss.ConsensusState("")
+	if err != nil {
+		t.Fatal(err)
+	} else if cs.WholeSigHash(txn, parentID, 0, 0, nil) != online.WholeSigHash(txn, parentID, 0, 0, nil) {
+		t.Fatal("sig hash mismatch")
+	}
+
+	// so should a chain index on the default network
+	ss = SigningState{Index: &online.Index}
+	cs, err = ss.ConsensusState(Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	} else if cs.Network.Name != Mainnet {
+		t.Fatalf("expected mainnet, got %q", cs.Network.Name)
+	} else if cs.WholeSigHash(txn, parentID, 0, 0, nil) != online.WholeSigHash(txn, parentID, 0, 0, nil) {
+		t.Fatal("sig hash mismatch")
+	}
+
+	// moving the allow height below the index changes the replay prefix
+	allow, require := uint64(1), uint64(2)
+	ss.Hardforks = &Hardforks{V2AllowHeight: &allow, V2RequireHeight: &require}
+	cs, err = ss.ConsensusState(Mainnet)
+	if err != nil {
+		t.Fatal(err)
+	} else if cs.WholeSigHash(txn, parentID, 0, 0, nil) == online.WholeSigHash(txn, parentID, 0, 0, nil) {
+		t.Fatal("expected sig hash to change")
+	} else if p, _ := Lookup(Mainnet); p.Network.HardforkV2.AllowHeight == allow {
+		t.Fatal("override modified the shared network")
+	}
+
+	// invalid descriptions
+	if _, err := (SigningState{}).ConsensusState(Mainnet); err == nil {
+		t.Fatal("expected missing state error")
+	} else if _, err := (SigningState{State: &online, Index: &online.Index}).ConsensusState(Mainnet); err == nil {
+		t.Fatal("expected ambiguous state error")
+	} else if _, err := (SigningState{Network: "foo", Index: &online.Index}).ConsensusState(Mainnet); err == nil {
+		t.Fatal("expected unknown network error")
+	}
+	require = 0
+	if _, err := (SigningState{Index: &online.Index, Hardforks: &Hardforks{V2RequireHeight: &require}}).ConsensusState(Mainnet); err == nil {
+		t.Fatal("expected invalid hardfork error")
+	}
+}
@@ -0,0 +1,5 @@
+//go:build testnet
+
+package network
+
+const defaultNetwork = Zen
@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"math/big"
+	"math"
+	"math/bits"
+	"slices"
 	"strings"
-	"unicode/utf8"
 
 	"go.sia.tech/core/types"
 	"golang.org/x/text/unicode/norm"
@@ -24,16 +25,105 @@ const (
 	dictionarySize = 1626
 )
 
+// A Language identifies a siad mnemonic dictionary.
+type Language string
+
+// Supported languages. The values match the dictionary IDs used by siad.
+const (
+	English  Language = "english"
+	German   Language = "german"
+	Japanese Language = "japanese"
+)
+
 var (
 	// ErrSeedLength is returned when the seed does not have the expected
 	// length of 38 bytes (32 bytes of entropy and 6 bytes of checksum).
 	ErrSeedLength = errors.New("invalid length")
 
 	// ErrUnknownWord is returned when a word in the phrase is not found in the
-	// english dictionary.
+	// dictionary.
 	ErrUnknownWord = errors.New("word not found")
+
+	// ErrChecksum is returned when the phrase's checksum does not match its
+	// entropy.
+	ErrChecksum = errors.New("invalid checksum")
+
+	// ErrUnknownLanguage is returned when a language is not supported.
+	ErrUnknownLanguage = errors.New("unknown language")
 )
 
+// A dictionary is a list of dictionarySize words. No two words share their
+// first prefixLen runes, ignoring case.
+type dictionary struct {
+	words     []string
+	prefixLen int
+	// index maps each word's prefix to its index
+	index map[string]int
+}
+
+var dictionaries = map[Language]dictionary{
+	English:  newDictionary(englishDict, 3),
+	German:   newDictionary(germanDict, 4),
+	Japanese: newDictionary(japaneseDict, 3),
+}
+
+// newDictionary returns a dictionary with its prefix index.
+func newDictionary(words []string, prefixLen int) dictionary {
+	index := make(map[string]int, len(words))
+	for i, w := range words {
+		index[wordPrefix(w, prefixLen)] = i
+	}
+	return dictionary{words: words, prefixLen: prefixLen, index: index}
+}
+
+// Languages returns the supported languages, English first.
+func Languages() []Language {
+	return []Language{English, German, Japanese}
+}
+
+// lookupDictionary returns the dictionary of a language.
+func lookupDictionary(lang Language) (dictionary, error) {
+	d, ok := dictionaries[lang]
+	if !ok {
+		return dictionary{}, fmt.Errorf("%w: %q", ErrUnknownLanguage, lang)
+	}
+	return d, nil
+}
+
+// Words returns the dictionary of a language.
+func Words(lang Language) ([]string, error) {
+	d, err := lookupDictionary(lang)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(d.words), nil
+}
+
+// wordPrefix returns the first n runes of a word after NFC normalization
+// and lower casing.
+func wordPrefix(word string, n int) string {
+	word = strings.ToLower(norm.NFC.String(word))
+	for i := range word {
+		if n == 0 {
+			return word[:i]
+		}
+		n--
+	}
+	return word
+}
+
+// WordIndex returns the index of the word in a language's dictionary. Words
+// are matched by their first few runes after NFC normalization, ignoring
+// case, so the rest of the word may be misspelled or missing.
+func WordIndex(lang Language, word string) (int, bool) {
+	d, ok := dictionaries[lang]
+	if !ok {
+		return 0, false
+	}
+	j, ok := d.index[wordPrefix(word, d.prefixLen)]
+	return j, ok
+}
+
 // The conversion functions can be seen as changing the base of a number. A
 // []byte can actually be viewed as a slice of base-256 numbers, and a []dict
 // can be viewed as a slice of base-1626 numbers. The conversions are a little
@@ -47,108 +137,146 @@ var (
 //		{1, 0} -> 257
 //		{0, 1} -> 512
 //
-// Every possible []byte has a unique big.Int which represents it, and every
-// big.Int represents a unique []byte.
-
-// bytesToInt converts a byte slice to a big.Int in a way that preserves
-// leading 0s, and ensures there is a perfect 1:1 mapping between Int's and
-// []byte's.
-func bytesToInt(bs []byte) *big.Int {
-	base := big.NewInt(256)
-	exp := big.NewInt(1)
-	result := big.NewInt(-1)
-	for i := 0; i < len(bs); i++ {
-		tmp := big.NewInt(int64(bs[i]))
-		tmp.Add(tmp, big.NewInt(1))
-		tmp.Mul(tmp, exp)
-		exp.Mul(exp, base)
-		result.Add(result, tmp)
-	}
-	return result
-}
-
-// intToBytes conversts a big.Int to a []byte, following the conventions
-// documented at bytesToInt.
-func intToBytes(bi *big.Int) (bs []byte) {
-	base := big.NewInt(256)
-	for bi.Cmp(base) >= 0 {
-		i := new(big.Int).Mod(bi, base).Int64()
-		bs = append(bs, byte(i))
-		bi.Sub(bi, base)
-		bi.Div(bi, base)
-	}
-	bs = append(bs, byte(bi.Int64()))
-	return bs
+// Every possible []byte has a unique integer which represents it, and every
+// integer represents a unique []byte. Only 38-byte seeds and the 28 or 29
+// word phrases that encode them are converted, so the integers are held in a
+// fixed-width uint320 instead of a big.Int.
+
+// maxPhraseWords is the maximum number of words in a phrase that encodes 38
+// bytes. Larger phrases do not fit in a uint320.
+const maxPhraseWords = 29
+
+// A uint320 is a little-endian 320-bit unsigned integer, wide enough for
+// 38 bytes in either base.
+type uint320 [5]uint64
+
+// mulAdd sets x to x*m + a and reports whether the result overflowed.
+func (x *uint320) mulAdd(m, a uint64) bool {
+	carry := a
+	for i := range x {
+		hi, lo := bits.Mul64(x[i], m)
+		var c uint64
+		x[i], c = bits.Add64(lo, carry, 0)
+		carry = hi + c
+	}
+	return carry != 0
 }
 
-// phraseToInt coverts a phrase into a big.Int, using logic similar to
-// bytesToInt.
-func phraseToInt(p string) (*big.Int, error) {
-	const prefixLen = 3
+// divMod sets x to x/d and returns the remainder.
+func (x *uint320) divMod(d uint64) (r uint64) {
+	for i := len(x) - 1; i >= 0; i-- {
+		x[i], r = bits.Div64(r, x[i], d)
+	}
+	return r
+}
 
-	base := big.NewInt(1626)
-	exp := big.NewInt(1)
-	result := big.NewInt(-1)
-	for _, word := range strings.Fields(p) {
-		// Normalize the input.
-		word = norm.NFC.String(word)
-
-		// Get the first prefixLen runes from the string.
-		var prefix []byte
-		var runeCount int
-		for _, r := range word {
-			encR := make([]byte, utf8.RuneLen(r))
-			utf8.EncodeRune(encR, r)
-			prefix = append(prefix, encR...)
-
-			runeCount++
-			if runeCount == prefixLen {
-				break
-			}
+// dec decrements x. x must not be zero.
+func (x *uint320) dec() {
+	for i := range x {
+		x[i]--
+		if x[i] != math.MaxUint64 {
+			return
 		}
+	}
+}
 
-		// Find the index associated with the phrase.
-		var tmp *big.Int
-		found := false
-		for j, word := range dict {
-			if strings.HasPrefix(word, string(prefix)) {
-				tmp = big.NewInt(int64(j))
-				found = true
-				break
-			}
+// less reports whether x is less than v.
+func (x *uint320) less(v uint64) bool {
+	for _, limb := range x[1:] {
+		if limb != 0 {
+			return false
 		}
-		if !found {
-			return nil, fmt.Errorf("word %q: %w", word, ErrUnknownWord)
+	}
+	return x[0] < v
+}
+
+// digitsToInt converts base-b digits, least significant first, to an integer
+// in a way that preserves leading 0s, and ensures there is a perfect 1:1
+// mapping between integers and digit slices. ok is false if the result does
+// not fit in a uint320.
+func digitsToInt(digits []uint64, b uint64) (x uint320, ok bool) {
+	if len(digits) == 0 {
+		return x, false
+	}
+	// evaluate sum((d+1) * b^i) - 1 by Horner's method
+	for i := len(digits) - 1; i >= 0; i-- {
+		if x.mulAdd(b, digits[i]+1) {
+			return x, false
 		}
+	}
+	x.dec()
+	return x, true
+}
+
+// intToDigits converts an integer to base-b digits, following the
+// conventions documented at digitsToInt. digit is called with each digit,
+// least significant first.
+func intToDigits(x uint320, b uint64, digit func(uint64)) {
+	for !x.less(b) {
+		digit(x.divMod(b))
+		// the quotient is at least 1
+		x.dec()
+	}
+	digit(x[0])
+}
 
-		// Add the index to the int.
-		tmp.Add(tmp, big.NewInt(1))
-		tmp.Mul(tmp, exp)
-		exp.Mul(exp, base)
-		result.Add(result, tmp)
+// bytesToInt converts a byte slice of at most 38 bytes to an integer,
+// following the conventions documented at digitsToInt.
+func bytesToInt(bs []byte) uint320 {
+	var digits [entropyBytes + checksumBytes]uint64
+	for i, b := range bs {
+		digits[i] = uint64(b)
 	}
-	return result, nil
+	x, _ := digitsToInt(digits[:len(bs)], 256)
+	return x
+}
+
+// intToBytes converts an integer to a []byte, following the conventions
+// documented at digitsToInt.
+func intToBytes(x uint320) (bs []byte) {
+	bs = make([]byte, 0, 40)
+	intToDigits(x, 256, func(d uint64) { bs = append(bs, byte(d)) })
+	return bs
 }
 
-// intToPhrase converts a phrase into a big.Int, working in a fashion similar
-// to bytesToInt.
-func intToPhrase(bi *big.Int) string {
-	var words []string
-	base := big.NewInt(dictionarySize)
-	for bi.Cmp(base) >= 0 {
-		i := new(big.Int).Mod(bi, base).Int64()
-		words = append(words, dict[i])
-		bi.Sub(bi, base)
-		bi.Div(bi, base)
+// phraseToInt coverts a phrase into an integer, using logic similar to
+// bytesToInt.
+func phraseToInt(p string, lang Language) (uint320, error) {
+	var digits []uint64
+	for _, word := range strings.Fields(p) {
+		// Find the index associated with the phrase.
+		j, ok := WordIndex(lang, word)
+		if !ok {
+			return uint320{}, fmt.Errorf("word %q: %w", word, ErrUnknownWord)
+		}
+		digits = append(digits, uint64(j))
 	}
-	words = append(words, dict[bi.Int64()])
+	if len(digits) > maxPhraseWords {
+		return uint320{}, fmt.Errorf("expected at most %d words, got %d: %w", maxPhraseWords, len(digits), ErrSeedLength)
+	}
+	x, ok := digitsToInt(digits, dictionarySize)
+	if !ok {
+		return uint320{}, fmt.Errorf("empty phrase: %w", ErrSeedLength)
+	}
+	return x, nil
+}
+
+// intToPhrase converts an integer into a phrase, working in a fashion
+// similar to intToBytes.
+func intToPhrase(x uint320, dict []string) string {
+	words := make([]string, 0, maxPhraseWords)
+	intToDigits(x, dictionarySize, func(d uint64) { words = append(words, dict[d]) })
 	return strings.Join(words, " ")
 }
 
-// SeedFromPhrase derives a 32-byte seed from the supplied 28/29 word
-// siad recovery phrase.
-func SeedFromPhrase(seed *[32]byte, phrase string) error {
-	b, err := phraseToInt(phrase)
+// SeedFromPhraseLanguage derives a 32-byte seed from the supplied 28/29 word
+// siad recovery phrase in the given language.
+func SeedFromPhraseLanguage(seed *[32]byte, phrase string, lang Language) error {
+	if _, err := lookupDictionary(lang); err != nil {
+		return err
+	}
+
+	b, err := phraseToInt(phrase, lang)
 	if err != nil {
 		return err
 	}
@@ -158,23 +286,72 @@ func SeedFromPhrase(seed *[32]byte, phrase string) error {
 	}
 	checksum := types.HashBytes(bs[:32])
 	if !bytes.Equal(checksum[:checksumBytes], bs[entropyBytes:]) {
-		return fmt.Errorf("invalid checksum: expected %x, got %x", checksum[:checksumBytes], bs[entropyBytes:])
+		return fmt.Errorf("expected %x, got %x: %w", checksum[:checksumBytes], bs[entropyBytes:], ErrChecksum)
 	}
 	copy(seed[:], bs)
 	return nil
 }
 
-// SeedToPhrase converts a 32-byte seed into a checksummed 28/29 word siad recovery phrase.
-func SeedToPhrase(seed *[32]byte) string {
+// seedFromPhrase tries each language whose dictionary contains every word
+// until one passes the checksum. If none does, the error of the first
+// language containing every word, or of English, is returned.
+func seedFromPhrase(seed *[32]byte, phrase string) (Language, error) {
+	var firstErr error
+	for _, lang := range Languages() {
+		err := SeedFromPhraseLanguage(seed, phrase, lang)
+		if err == nil {
+			return lang, nil
+		} else if firstErr == nil || (errors.Is(firstErr, ErrUnknownWord) && !errors.Is(err, ErrUnknownWord)) {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
+
+// SeedFromPhrase derives a 32-byte seed from the supplied 28/29 word
+// siad recovery phrase. The phrase's language is detected.
+func SeedFromPhrase(seed *[32]byte, phrase string) error {
+	_, err := seedFromPhrase(seed, phrase)
+	return err
+}
+
+// DetectLanguage returns the language of a valid 28/29 word siad recovery
+// phrase.
+func DetectLanguage(phrase string) (Language, error) {
+	var seed [32]byte
+	defer clear(seed[:])
+	return seedFromPhrase(&seed, phrase)
+}
+
+// SeedToPhraseLanguage converts a 32-byte seed into a checksummed 28/29 word
+// siad recovery phrase in the given language.
+func SeedToPhraseLanguage(seed *[32]byte, lang Language) (string, error) {
+	d, err := lookupDictionary(lang)
+	if err != nil {
+		return "", err
+	}
 	checksum := types.HashBytes(seed[:])
 	checksumSeed := append(seed[:], checksum[:checksumBytes]...)
-	return intToPhrase(bytesToInt(checksumSeed))
+	return intToPhrase(bytesToInt(checksumSeed), d.words), nil
 }
 
-// NewSeedPhrase generates a new 28/29 word siad recovery phrase from a random 32-byte seed.
-func NewSeedPhrase() string {
+// SeedToPhrase converts a 32-byte seed into a checksummed 28/29 word siad recovery phrase.
+func SeedToPhrase(seed *[32]byte) string {
+	phrase, _ := SeedToPhraseLanguage(seed, English)
+	return phrase
+}
+
+// NewSeedPhraseLanguage generates a new 28/29 word siad recovery phrase in
+// the given language from a random 32-byte seed.
+func NewSeedPhraseLanguage(lang Language) (string, error) {
 	entropy := frand.Entropy256()
 	defer clear(entropy[:])
 
-	return SeedToPhrase(&entropy)
+	return SeedToPhraseLanguage(&entropy, lang)
+}
+
+// NewSeedPhrase generates a new 28/29 word siad recovery phrase from a random 32-byte seed.
+func NewSeedPhrase() string {
+	phrase, _ := NewSeedPhraseLanguage(English)
+	return phrase
 }
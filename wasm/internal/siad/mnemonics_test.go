@@ -3,10 +3,15 @@ package siad
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
 	"testing"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/coreutils/wallet"
+	"golang.org/x/text/unicode/norm"
+	"lukechampine.com/frand"
 )
 
 func TestMnemonic28(t *testing.T) {
@@ -94,3 +99,275 @@ func TestMnemonic29(t *testing.T) {
 		t.Fatalf("roundtrip failed: expected %q, got %q", phrase, roundtrip)
 	}
 }
+
+func TestMnemonicLanguages(t *testing.T) {
+	// the seed of TestMnemonic28 in each language
+	expectedSeed, err := hex.DecodeString("0da9b0562d06a6142a5e7f5a8073e01631981a7968c6070f7e4e177ae333f3c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phrases := map[Language]string{
+		German:   "Nestbau Biotop Fortgang Gesetz Vollblut Habicht Logik Labor Weißbier Unruhe polieren Argument Geige treffen Nessel bewirken Detektiv Kiosk besorgen Donner Defekt verhexen Limonade Bescheid begrüßen beizen Fügung Bikini",
+		Japanese: "ちしき おかず こなごな さてい はくしゅ じかん たいそう そつえん はなす のらいぬ てすり いろえんぴつ さくし ねんまつ ちさい おうえん きかい せりふ えんげき きすう かんたん はいそう だいがく えんかい えいせい えくせる こもの おえる",
+	}
+
+	for lang, phrase := range phrases {
+		t.Run(string(lang), func(t *testing.T) {
+			d := dictionaries[lang]
+			prefixes := strings.Fields(phrase)
+			for i, w := range prefixes {
+				prefixes[i] = string([]rune(w)[:d.prefixLen])
+			}
+
+			variants := []string{
+				phrase,
+				strings.ToLower(phrase),
+				norm.NFD.String(phrase),
+				strings.Join(prefixes, " "),
+			}
+			for _, variant := range variants {
+				var seed [32]byte
+				if err := SeedFromPhrase(&seed, variant); err != nil {
+					t.Fatalf("%q: %v", variant, err)
+				} else if !bytes.Equal(seed[:], expectedSeed) {
+					t.Fatalf("unexpected seed: expected %x, got %x", expectedSeed, seed)
+				} else if detected, err := DetectLanguage(variant); err != nil {
+					t.Fatal(err)
+				} else if detected != lang {
+					t.Fatalf("expected language %q, got %q", lang, detected)
+				}
+			}
+
+			seed := [32]byte(expectedSeed)
+			if roundtrip, err := SeedToPhraseLanguage(&seed, lang); err != nil {
+				t.Fatal(err)
+			} else if roundtrip != phrase {
+				t.Fatalf("roundtrip failed: expected %q, got %q", phrase, roundtrip)
+			}
+		})
+	}
+
+	for _, lang := range Languages() {
+		phrase, err := NewSeedPhraseLanguage(lang)
+		if err != nil {
+			t.Fatal(err)
+		} else if detected, err := DetectLanguage(phrase); err != nil {
+			t.Fatal(err)
+		} else if detected != lang {
+			t.Fatalf("expected language %q, got %q", lang, detected)
+		}
+	}
+
+	if _, err := NewSeedPhraseLanguage("klingon"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Fatalf("expected ErrUnknownLanguage, got %v", err)
+	}
+}
+
+func TestDictionaries(t *testing.T) {
+	for _, lang := range Languages() {
+		d := dictionaries[lang]
+		if len(d.words) != dictionarySize {
+			t.Fatalf("%s: expected %d words, got %d", lang, dictionarySize, len(d.words))
+		}
+
+		seen := make(map[string]bool)
+		for i, w := range d.words {
+			if !norm.NFC.IsNormalString(w) {
+				t.Fatalf("%s: word %q is not NFC normalized", lang, w)
+			}
+			prefix := wordPrefix(w, d.prefixLen)
+			if seen[prefix] {
+				t.Fatalf("%s: prefix %q of %q is not unique", lang, prefix, w)
+			}
+			seen[prefix] = true
+
+			if j, ok := WordIndex(lang, w); !ok || j != i {
+				t.Fatalf("%s: expected %q at %d, got %d", lang, w, i, j)
+			}
+		}
+	}
+}
+
+// refWordIndex, refBytesToInt, refIntToBytes and refPhraseToInt are the
+// original linear scan and big.Int conversions. They are used to check and
+// benchmark the prefix index and fixed-width conversions.
+
+func refWordIndex(d dictionary, word string) (int, bool) {
+	prefix := wordPrefix(word, d.prefixLen)
+	for j, w := range d.words {
+		if wordPrefix(w, d.prefixLen) == prefix {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+func refBytesToInt(bs []byte) *big.Int {
+	base := big.NewInt(256)
+	exp := big.NewInt(1)
+	result := big.NewInt(-1)
+	for i := 0; i < len(bs); i++ {
+		tmp := big.NewInt(int64(bs[i]))
+		tmp.Add(tmp, big.NewInt(1))
+		tmp.Mul(tmp, exp)
+		exp.Mul(exp, base)
+		result.Add(result, tmp)
+	}
+	return result
+}
+
+func refIntToBytes(bi *big.Int) (bs []byte) {
+	base := big.NewInt(256)
+	for bi.Cmp(base) >= 0 {
+		i := new(big.Int).Mod(bi, base).Int64()
+		bs = append(bs, byte(i))
+		bi.Sub(bi, base)
+		bi.Div(bi, base)
+	}
+	bs = append(bs, byte(bi.Int64()))
+	return bs
+}
+
+func refPhraseToInt(d dictionary, p string) (*big.Int, error) {
+	base := big.NewInt(dictionarySize)
+	exp := big.NewInt(1)
+	result := big.NewInt(-1)
+	for _, word := range strings.Fields(p) {
+		j, ok := refWordIndex(d, word)
+		if !ok {
+			return nil, ErrUnknownWord
+		}
+		tmp := big.NewInt(int64(j))
+		tmp.Add(tmp, big.NewInt(1))
+		tmp.Mul(tmp, exp)
+		exp.Mul(exp, base)
+		result.Add(result, tmp)
+	}
+	return result, nil
+}
+
+// toBig converts a uint320 to a big.Int.
+func toBig(x uint320) *big.Int {
+	var buf [40]byte
+	for i, limb := range x {
+		for j := range 8 {
+			buf[39-(8*i+j)] = byte(limb >> (8 * j))
+		}
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+func TestConversions(t *testing.T) {
+	d := dictionaries[English]
+	inputs := [][]byte{
+		make([]byte, 38),
+		bytes.Repeat([]byte{0xff}, 38),
+	}
+	for range 100 {
+		inputs = append(inputs, frand.Bytes(38))
+	}
+
+	for _, bs := range inputs {
+		x := bytesToInt(bs)
+		if toBig(x).Cmp(refBytesToInt(bs)) != 0 {
+			t.Fatalf("bytesToInt(%x): expected %v, got %v", bs, refBytesToInt(bs), toBig(x))
+		} else if rt := intToBytes(x); !bytes.Equal(rt, bs) {
+			t.Fatalf("roundtrip failed: expected %x, got %x", bs, rt)
+		}
+
+		phrase := intToPhrase(x, d.words)
+		if n := len(strings.Fields(phrase)); n != 28 && n != 29 {
+			t.Fatalf("expected 28 or 29 words, got %d", n)
+		}
+		px, err := phraseToInt(phrase, English)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := refPhraseToInt(d, phrase)
+		if err != nil {
+			t.Fatal(err)
+		} else if toBig(px).Cmp(ref) != 0 {
+			t.Fatalf("phraseToInt(%q): expected %v, got %v", phrase, ref, toBig(px))
+		} else if !bytes.Equal(refIntToBytes(ref), bs) {
+			t.Fatalf("reference roundtrip failed for %x", bs)
+		}
+	}
+
+	// phrases of the wrong length are rejected before they overflow
+	long := strings.Repeat("zones ", 40)
+	var seed [32]byte
+	if err := SeedFromPhrase(&seed, long); !errors.Is(err, ErrSeedLength) {
+		t.Fatalf("expected ErrSeedLength, got %v", err)
+	} else if err := SeedFromPhrase(&seed, "abbey"); !errors.Is(err, ErrSeedLength) {
+		t.Fatalf("expected ErrSeedLength, got %v", err)
+	}
+}
+
+func BenchmarkWordIndex(b *testing.B) {
+	d := dictionaries[English]
+	word := d.words[len(d.words)-1]
+
+	b.Run("index", func(b *testing.B) {
+		for b.Loop() {
+			WordIndex(English, word)
+		}
+	})
+
+	b.Run("scan", func(b *testing.B) {
+		for b.Loop() {
+			refWordIndex(d, word)
+		}
+	})
+}
+
+func BenchmarkSeedFromPhrase(b *testing.B) {
+	phrase := NewSeedPhrase()
+
+	b.Run("fixed", func(b *testing.B) {
+		var seed [32]byte
+		for b.Loop() {
+			if err := SeedFromPhraseLanguage(&seed, phrase, English); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("big", func(b *testing.B) {
+		d := dictionaries[English]
+		for b.Loop() {
+			bi, err := refPhraseToInt(d, phrase)
+			if err != nil {
+				b.Fatal(err)
+			}
+			refIntToBytes(bi)
+		}
+	})
+}
+
+func BenchmarkSeedToPhrase(b *testing.B) {
+	seed := frand.Entropy256()
+	checksum := types.HashBytes(seed[:])
+	checksumSeed := append(seed[:], checksum[:checksumBytes]...)
+
+	b.Run("fixed", func(b *testing.B) {
+		for b.Loop() {
+			SeedToPhrase(&seed)
+		}
+	})
+
+	b.Run("big", func(b *testing.B) {
+		for b.Loop() {
+			bi := refBytesToInt(checksumSeed)
+			var words []string
+			base := big.NewInt(dictionarySize)
+			for bi.Cmp(base) >= 0 {
+				i := new(big.Int).Mod(bi, base).Int64()
+				words = append(words, englishDict[i])
+				bi.Sub(bi, base)
+				bi.Div(bi, base)
+			}
+			words = append(words, englishDict[bi.Int64()])
+			_ = strings.Join(words, " ")
+		}
+	})
+}
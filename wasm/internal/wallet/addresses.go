@@ -0,0 +1,239 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// Address types
+const (
+	// AddressTypeUnlockConditions is the v1 address of the key's standard
+	// unlock conditions. It can be spent by v1 and v2 transactions.
+	AddressTypeUnlockConditions AddressType = "unlock_conditions"
+	// AddressTypePublicKey is the v2 address of the key's PolicyPublicKey
+	// spend policy. It can only be spent by v2 transactions.
+	AddressTypePublicKey AddressType = "public_key"
+)
+
+type (
+	// An AddressType selects which address is derived for a key. The same
+	// key has a different address for each type.
+	AddressType string
+
+	// An Address is a wallet address derived from a seed.
+	Address struct {
+		// Type is empty for addresses derived before v2 policy addresses were
+		// supported. It is treated as AddressTypeUnlockConditions.
+		Type AddressType `json:"address_type,omitempty"`
+		// UnlockConditions are only set for AddressTypeUnlockConditions.
+		UnlockConditions types.UnlockConditions `json:"unlock_conditions,omitzero"`
+		PublicKey        types.PublicKey        `json:"public_key,omitzero"`
+		UsageType        string                 `json:"usage_type"`
+		Address          types.Address          `json:"address"`
+		Index            uint64                 `json:"index"`
+	}
+
+	// RecoveryProgress is reported after each batch of addresses is checked
+	// during recovery.
+	RecoveryProgress struct {
+		Found     int       `json:"found"`
+		Addresses []Address `json:"addresses"`
+		Index     uint64    `json:"index"`
+	}
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *AddressType) UnmarshalText(b []byte) error {
+	switch AddressType(b) {
+	case "", AddressTypeUnlockConditions, AddressTypePublicKey:
+		*t = AddressType(b)
+		return nil
+	default:
+		return fmt.Errorf("unknown address type %q", b)
+	}
+}
+
+// validate checks that the address matches its unlock conditions or public
+// key.
+func (a Address) validate() error {
+	switch a.Type {
+	case "", AddressTypeUnlockConditions:
+		if a.UnlockConditions.UnlockHash() != a.Address {
+			return fmt.Errorf("unlock conditions do not match address %v", a.Address)
+		}
+	case AddressTypePublicKey:
+		if types.PolicyPublicKey(a.PublicKey).Address() != a.Address {
+			return fmt.Errorf("public key does not match address %v", a.Address)
+		}
+	default:
+		return fmt.Errorf("unknown address type %q", a.Type)
+	}
+	return nil
+}
+
+// keyOwnsAddress returns true if addr is the v1 or v2 address of the key.
+func keyOwnsAddress(pk types.PublicKey, addr types.Address) bool {
+	return types.StandardUnlockHash(pk) == addr || types.PolicyPublicKey(pk).Address() == addr
+}
+
+// v1Spendable returns true if outputs sent to the address can be spent by v1
+// transactions.
+func (a Address) v1Spendable() bool {
+	return a.Type != AddressTypePublicKey
+}
+
+// GenerateAddress derives the v1 unlock conditions address at index i from
+// the seed.
+func GenerateAddress(seed *[32]byte, i uint64) Address {
+	sk := wallet.KeyFromSeed(seed, i)
+	return Address{
+		Type:             AddressTypeUnlockConditions,
+		UnlockConditions: types.StandardUnlockConditions(sk.PublicKey()),
+		PublicKey:        sk.PublicKey(),
+		UsageType:        "sent",
+		Address:          types.StandardUnlockHash(sk.PublicKey()),
+		Index:            i,
+	}
+}
+
+// GeneratePolicyAddress derives the v2 public key policy address at index i
+// from the seed.
+func GeneratePolicyAddress(seed *[32]byte, i uint64) Address {
+	sk := wallet.KeyFromSeed(seed, i)
+	return Address{
+		Type:      AddressTypePublicKey,
+		PublicKey: sk.PublicKey(),
+		UsageType: "sent",
+		Address:   types.PolicyPublicKey(sk.PublicKey()).Address(),
+		Index:     i,
+	}
+}
+
+// GenerateTypedAddress derives the address of type t at index i from the
+// seed.
+func GenerateTypedAddress(seed *[32]byte, i uint64, t AddressType) (Address, error) {
+	switch t {
+	case "", AddressTypeUnlockConditions:
+		return GenerateAddress(seed, i), nil
+	case AddressTypePublicKey:
+		return GeneratePolicyAddress(seed, i), nil
+	default:
+		return Address{}, fmt.Errorf("unknown address type %q", t)
+	}
+}
+
+// GenerateAddresses derives n v1 addresses from the seed starting at index
+// i.
+func GenerateAddresses(seed *[32]byte, i uint64, n int) []Address {
+	addresses := make([]Address, 0, n)
+	for ; n > len(addresses); i++ {
+		addresses = append(addresses, GenerateAddress(seed, i))
+	}
+	return addresses
+}
+
+// GenerateTypedAddresses derives n addresses of type t from the seed
+// starting at index i.
+func GenerateTypedAddresses(seed *[32]byte, i uint64, n int, t AddressType) ([]Address, error) {
+	addresses := make([]Address, 0, n)
+	for ; n > len(addresses); i++ {
+		addr, err := GenerateTypedAddress(seed, i, t)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, addr)
+	}
+	return addresses, nil
+}
+
+// ScanAddresses returns every address that funds sent to a wallet address
+// could have been sent to: both the v1 and v2 address of each key. Addresses
+// without a known public key are returned as is.
+func ScanAddresses(addresses []Address) []types.Address {
+	seen := make(map[types.Address]bool)
+	scan := make([]types.Address, 0, 2*len(addresses))
+	add := func(addr types.Address) {
+		if !seen[addr] {
+			seen[addr] = true
+			scan = append(scan, addr)
+		}
+	}
+	for _, addr := range addresses {
+		add(addr.Address)
+
+		pk := addr.PublicKey
+		if pk == (types.PublicKey{}) {
+			// addresses derived before the public key was stored only
+			// carry their unlock conditions
+			uc := addr.UnlockConditions
+			if len(uc.PublicKeys) != 1 || uc.SignaturesRequired != 1 || uc.Timelock != 0 {
+				continue
+			}
+			var ok bool
+			if pk, ok = unlockKeyToPublicKey(uc.PublicKeys[0]); !ok {
+				continue
+			}
+		}
+		add(types.StandardUnlockHash(pk))
+		add(types.PolicyPublicKey(pk).Address())
+	}
+	return scan
+}
+
+// RecoverAddresses scans the seed's addresses starting at startIndex until
+// lookahead consecutive keys have not been seen on chain. Both the v1 and v2
+// address of each key are checked, but only the address types seen on chain
+// in a batch are recovered. progress is called after each batch of addresses
+// is checked.
+func RecoverAddresses(b backend.Backend, seed *[32]byte, startIndex, lookahead, lastKnownIndex uint64, progress func(RecoveryProgress)) error {
+	var gap uint64
+	n := min(500, lookahead)
+	lastSeenIndex := lastKnownIndex
+	for i := startIndex; gap < lookahead; i += n {
+		// the v1 and v2 addresses are checked as separate groups so an
+		// unused type is not recovered
+		typed := [2][]Address{make([]Address, 0, n), make([]Address, 0, n)}
+		groups := [][]types.Address{make([]types.Address, 0, n), make([]types.Address, 0, n)}
+		start, end := i, i+n
+		for i := start; i < end; i++ {
+			for j, addr := range []Address{GenerateAddress(seed, i), GeneratePolicyAddress(seed, i)} {
+				typed[j] = append(typed[j], addr)
+				groups[j] = append(groups[j], addr.Address)
+			}
+		}
+
+		log.Println("checking addresses from", i, "to", i+uint64(n))
+		used, err := findUsedGroups(context.Background(), b, groups)
+		if err != nil {
+			return err
+		}
+		var recovered []Address
+		for j := range typed {
+			if used[j] {
+				recovered = append(recovered, typed[j]...)
+			}
+		}
+		if len(recovered) == 0 {
+			// if no used addresses are found, increase the gap
+			gap += uint64(n)
+			log.Printf("no used addresses found, increasing gap to %d", gap)
+		} else {
+			// reset gap if used addresses are found
+			lastSeenIndex = end
+			gap = 0
+			log.Println("found used addresses, gap reset")
+		}
+
+		progress(RecoveryProgress{
+			Found:     len(recovered),
+			Addresses: recovered,
+			Index:     lastSeenIndex,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package wallet
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+	"lukechampine.com/frand"
+)
+
+const testPhrase = "rodent colony illness junk waist leopard pierce oust wield viewpoint slackens axis jittery vampire rockets cistern eels oaks cell emotion eagle vortex pests cedar business cactus inorganic cocoa"
+
+// testState returns a mainnet consensus state at the given height.
+func testState(height uint64) consensus.State {
+	n, _ := chain.Mainnet()
+	cs := n.GenesisState()
+	cs.Index = types.ChainIndex{Height: height, ID: frand.Entropy256()}
+	return cs
+}
+
+// testSeed returns the seed of testPhrase.
+func testSeed(t *testing.T) *[32]byte {
+	t.Helper()
+
+	var seed [32]byte
+	if err := PhraseToSeed(testPhrase, &seed); err != nil {
+		t.Fatal(err)
+	}
+	return &seed
+}
+
+func TestPhraseToSeed(t *testing.T) {
+	for _, seedType := range []string{SeedTypeSia, SeedTypeWalrus} {
+		phrase, err := NewSeedPhrase(seedType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var seed [32]byte
+		if err := PhraseToSeed(phrase, &seed); err != nil {
+			t.Fatalf("%s: %v", seedType, err)
+		}
+	}
+
+	if _, err := NewSeedPhrase("foo"); err == nil {
+		t.Fatal("expected unknown seed type error")
+	}
+
+	var seed [32]byte
+	if err := PhraseToSeed("abbey abbey abbey", &seed); err == nil {
+		t.Fatal("expected invalid length error")
+	}
+}
+
+func TestGenerateAddresses(t *testing.T) {
+	seed := testSeed(t)
+
+	addresses := GenerateAddresses(seed, 0, 3)
+	expected := []string{
+		"46446ff0e159e326d9794bb9814744cf50e4c3138874b42b72e849392f096bc6e5398dc9b3e9",
+		"2a3236826809a14ae4fed7d461f148762711152e1bacd564079e2fe98fa24830c91c9ea5b3d1",
+		"362c160b779ed1d6150f2292a25cbe0c7b630c0d129d1622a6e366c3e8d4bdfeb64ca30c61a5",
+	}
+	if len(addresses) != len(expected) {
+		t.Fatalf("expected %d addresses, got %d", len(expected), len(addresses))
+	}
+	for i, addr := range addresses {
+		if addr.Index != uint64(i) {
+			t.Fatalf("expected index %d, got %d", i, addr.Index)
+		} else if addr.Address.String() != expected[i] {
+			t.Fatalf("expected address %q, got %q", expected[i], addr.Address)
+		} else if addr.UnlockConditions.UnlockHash() != addr.Address {
+			t.Fatalf("unlock conditions do not match address %d", i)
+		}
+	}
+}
+
+func TestRecoverAddresses(t *testing.T) {
+	seed := testSeed(t)
+	b := backend.NewMemory(testState(1000))
+
+	// mark a v1 and a v2 address in separate batches as used
+	for _, addr := range []Address{GenerateAddress(seed, 10), GeneratePolicyAddress(seed, 700)} {
+		b.AddSiacoinElements(types.SiacoinElement{
+			ID: frand.Entropy256(),
+			SiacoinOutput: types.SiacoinOutput{
+				Address: addr.Address,
+				Value:   types.Siacoins(1),
+			},
+		})
+	}
+
+	var batches int
+	var recovered []Address
+	var lastIndex uint64
+	err := RecoverAddresses(b, seed, 0, 1000, 0, func(progress RecoveryProgress) {
+		batches++
+		recovered = append(recovered, progress.Addresses...)
+		lastIndex = progress.Index
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if batches != 4 {
+		t.Fatalf("expected 4 batches, got %d", batches)
+	} else if len(recovered) != 1000 {
+		// only the type used in each batch is recovered
+		t.Fatalf("expected 1000 addresses, got %d", len(recovered))
+	} else if lastIndex != 1000 {
+		t.Fatalf("expected last index 1000, got %d", lastIndex)
+	}
+	for _, addr := range recovered {
+		expected := AddressTypeUnlockConditions
+		if addr.Index >= 500 {
+			expected = AddressTypePublicKey
+		}
+		if addr.Type != expected {
+			t.Fatalf("expected key %d to be recovered as %q, got %q", addr.Index, expected, addr.Type)
+		}
+	}
+}
+
+func TestGenerateTypedAddresses(t *testing.T) {
+	seed := testSeed(t)
+
+	v1, err := GenerateTypedAddresses(seed, 0, 2, AddressTypeUnlockConditions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := GenerateTypedAddresses(seed, 0, 2, AddressTypePublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range v1 {
+		if v1[i].PublicKey != v2[i].PublicKey {
+			t.Fatalf("expected the same key for index %d", i)
+		} else if v1[i].Address == v2[i].Address {
+			t.Fatalf("expected different addresses for index %d", i)
+		} else if v2[i].Address != types.PolicyPublicKey(v2[i].PublicKey).Address() {
+			t.Fatalf("policy address %d does not match its key", i)
+		} else if err := v2[i].validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := GenerateTypedAddresses(seed, 0, 1, "p2pkh"); err == nil {
+		t.Fatal("expected unknown type error")
+	}
+
+	// addresses stored before the public key was recorded are expanded from
+	// their unlock conditions
+	legacy := v1[0]
+	legacy.Type, legacy.PublicKey = "", types.PublicKey{}
+	scan := ScanAddresses([]Address{legacy, v2[1]})
+	expected := []types.Address{v1[0].Address, v2[0].Address, v1[1].Address, v2[1].Address}
+	if len(scan) != len(expected) {
+		t.Fatalf("expected %d addresses, got %d", len(expected), len(scan))
+	}
+	for _, addr := range expected {
+		if !slices.Contains(scan, addr) {
+			t.Fatalf("expected %v to be scanned", addr)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+)
+
+type (
+	// A SiacoinOutput is an unspent siacoin output owned by the wallet.
+	SiacoinOutput struct {
+		OutputID   types.SiacoinOutputID `json:"output_id"`
+		UnlockHash types.Address         `json:"unlock_hash"`
+		Value      types.Currency        `json:"value"`
+	}
+
+	// A SiafundOutput is an unspent siafund output owned by the wallet.
+	SiafundOutput struct {
+		OutputID   types.SiafundOutputID `json:"output_id"`
+		UnlockHash types.Address         `json:"unlock_hash"`
+		Value      uint64                `json:"value"`
+	}
+
+	// A Balance summarizes the balance, transactions and unspent outputs of a
+	// set of wallet addresses.
+	Balance struct {
+		SiafundClaim            types.Currency         `json:"siafund_claim"`
+		Transactions            []ProcessedTransaction `json:"transactions"`
+		UnspentSiacoinOutputs   []SiacoinOutput        `json:"unspent_siacoin_outputs"`
+		UnspentSiafundOutputs   []SiafundOutput        `json:"unspent_siafund_outputs"`
+		ConfirmedSiacoinBalance types.Currency         `json:"confirmed_siacoin_balance"`
+		ConfirmedSiafundBalance uint64                 `json:"confirmed_siafund_balance"`
+		UnconfirmedSiacoinDelta types.Currency         `json:"unconfirmed_siacoin_delta"`
+		UnconfirmedSiafundDelta types.Currency         `json:"unconfirmed_siafund_delta"`
+	}
+)
+
+// WalletBalance returns the confirmed siacoin and siafund balance of the
+// addresses.
+func WalletBalance(w backend.Backend, addresses []types.Address) (wb Balance, err error) {
+	batch := min(1000, len(addresses))
+	for i := 0; i < len(addresses); i += batch {
+		addressBatch := addresses[i:min(i+batch, len(addresses))]
+		balance, err := w.BatchAddressBalance(addressBatch)
+		if err != nil {
+			return Balance{}, fmt.Errorf("failed to get wallet balance: %w", err)
+		}
+		wb.ConfirmedSiacoinBalance = wb.ConfirmedSiacoinBalance.Add(balance.Siacoins)
+		wb.ConfirmedSiafundBalance += balance.Siafunds
+	}
+	return wb, nil
+}
+
+// WalletSiacoinOutputs returns the mature unspent siacoin outputs of the
+// addresses.
+func WalletSiacoinOutputs(w backend.Backend, addresses []types.Address) ([]SiacoinOutput, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	tip, err := w.ConsensusTip()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consensus state: %w", err)
+	}
+
+	relevantAddresses := make(map[types.Address]bool, len(addresses))
+	for _, addr := range addresses {
+		relevantAddresses[addr] = true
+	}
+
+	const addressBatchSize = 100
+	const outputPageSize = 100
+
+	var utxos []SiacoinOutput
+	for i := 0; i < len(addresses); i += addressBatchSize {
+		addressBatch := addresses[i:min(i+addressBatchSize, len(addresses))]
+		for offset := 0; ; offset += outputPageSize {
+			sces, _, err := w.BatchAddressSiacoinOutputs(addressBatch, offset, outputPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get wallet siacoin outputs: %w", err)
+			}
+			for _, sce := range sces {
+				if sce.MaturityHeight > tip.Height {
+					continue
+				}
+				utxos = append(utxos, SiacoinOutput{
+					OutputID:   sce.ID,
+					UnlockHash: sce.SiacoinOutput.Address,
+					Value:      sce.SiacoinOutput.Value,
+				})
+			}
+			if len(sces) < outputPageSize {
+				break
+			}
+		}
+	}
+	return utxos, nil
+}
+
+// WalletSiafundOutputs returns the unspent siafund outputs of the addresses
+// and their total unclaimed siafund revenue.
+func WalletSiafundOutputs(w backend.Backend, addresses []types.Address) ([]SiafundOutput, types.Currency, error) {
+	if len(addresses) == 0 {
+		return nil, types.ZeroCurrency, nil
+	}
+
+	cs, err := w.ConsensusTipState()
+	if err != nil {
+		return nil, types.ZeroCurrency, fmt.Errorf("failed to get consensus state: %w", err)
+	}
+	log.Println("tax revenue", cs.SiafundTaxRevenue)
+
+	relevantAddresses := make(map[types.Address]bool, len(addresses))
+	for _, addr := range addresses {
+		relevantAddresses[addr] = true
+	}
+
+	const addressBatchSize = 100
+	const outputPageSize = 100
+
+	var claimBalance types.Currency
+	var utxos []SiafundOutput
+	for i := 0; i < len(addresses); i += addressBatchSize {
+		addressBatch := addresses[i:min(i+addressBatchSize, len(addresses))]
+		for offset := 0; ; offset += outputPageSize {
+			sfes, _, err := w.BatchAddressSiafundOutputs(addressBatch, offset, outputPageSize)
+			if err != nil {
+				return nil, types.ZeroCurrency, fmt.Errorf("failed to get wallet siafund outputs: %w", err)
+			}
+			for _, sfe := range sfes {
+				dividend := cs.SiafundTaxRevenue.Sub(sfe.ClaimStart).Div64(cs.SiafundCount()).Mul64(sfe.SiafundOutput.Value)
+				log.Println("siafund", sfe.ID, sfe.ClaimStart, dividend)
+				claimBalance = claimBalance.Add(dividend)
+				utxos = append(utxos, SiafundOutput{
+					OutputID:   sfe.ID,
+					UnlockHash: sfe.SiafundOutput.Address,
+					Value:      sfe.SiafundOutput.Value,
+				})
+			}
+			if len(sfes) < outputPageSize {
+				break
+			}
+		}
+	}
+	return utxos, claimBalance, nil
+}
+
+// WalletSummary returns the balance, recent transactions and unspent outputs
+// of the addresses.
+func WalletSummary(w backend.Backend, addresses []types.Address) (Balance, error) {
+	balance, err := WalletBalance(w, addresses)
+	if err != nil {
+		return Balance{}, fmt.Errorf("error getting wallet balance: %w", err)
+	}
+
+	balance.Transactions, err = WalletTransactions(w, addresses)
+	if err != nil {
+		return Balance{}, fmt.Errorf("error getting wallet transactions: %w", err)
+	}
+
+	balance.UnspentSiacoinOutputs, err = WalletSiacoinOutputs(w, addresses)
+	if err != nil {
+		return Balance{}, fmt.Errorf("error getting wallet siacoin outputs: %w", err)
+	}
+
+	balance.UnspentSiafundOutputs, balance.SiafundClaim, err = WalletSiafundOutputs(w, addresses)
+	if err != nil {
+		return Balance{}, fmt.Errorf("error getting wallet siafund outputs: %w", err)
+	}
+	return balance, nil
+}
+
+// unspentElements returns the confirmed unspent siacoin elements of the
+// addresses, and their siafund elements if siafunds is set, along with the
+// chain index the elements' proofs are valid at. It fails if the chain tip
+// changes between pages, since the proofs would no longer match.
+func unspentElements(w backend.Backend, addresses []types.Address, siafunds bool) (types.ChainIndex, []types.SiacoinElement, []types.SiafundElement, error) {
+	const addressBatchSize = 100
+	const outputPageSize = 100
+
+	var basis types.ChainIndex
+	checkBasis := func(pageBasis types.ChainIndex) error {
+		if basis != (types.ChainIndex{}) && pageBasis != basis {
+			return errors.New("chain tip changed while fetching outputs, try again")
+		}
+		basis = pageBasis
+		return nil
+	}
+
+	var sces []types.SiacoinElement
+	var sfes []types.SiafundElement
+	for i := 0; i < len(addresses); i += addressBatchSize {
+		addressBatch := addresses[i:min(i+addressBatchSize, len(addresses))]
+		for offset := 0; ; offset += outputPageSize {
+			page, pageBasis, err := w.BatchAddressSiacoinOutputs(addressBatch, offset, outputPageSize)
+			if err != nil {
+				return types.ChainIndex{}, nil, nil, fmt.Errorf("failed to get wallet siacoin outputs: %w", err)
+			} else if err := checkBasis(pageBasis); err != nil {
+				return types.ChainIndex{}, nil, nil, err
+			}
+			for _, sce := range page {
+				sces = append(sces, sce.SiacoinElement)
+			}
+			if len(page) < outputPageSize {
+				break
+			}
+		}
+		for offset := 0; siafunds; offset += outputPageSize {
+			page, pageBasis, err := w.BatchAddressSiafundOutputs(addressBatch, offset, outputPageSize)
+			if err != nil {
+				return types.ChainIndex{}, nil, nil, fmt.Errorf("failed to get wallet siafund outputs: %w", err)
+			} else if err := checkBasis(pageBasis); err != nil {
+				return types.ChainIndex{}, nil, nil, err
+			}
+			for _, sfe := range page {
+				sfes = append(sfes, sfe.SiafundElement)
+			}
+			if len(page) < outputPageSize {
+				break
+			}
+		}
+	}
+
+	if basis == (types.ChainIndex{}) {
+		tip, err := w.ConsensusTip()
+		if err != nil {
+			return types.ChainIndex{}, nil, nil, fmt.Errorf("failed to get consensus tip: %w", err)
+		}
+		basis = tip
+	}
+	return basis, sces, sfes, nil
+}
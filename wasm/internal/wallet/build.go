@@ -0,0 +1,370 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+type (
+	// A Recipient is an address and the amount of siacoins to send to it.
+	Recipient struct {
+		Address types.Address  `json:"address"`
+		Value   types.Currency `json:"value"`
+	}
+
+	// A BuildRequest describes a v2 transaction to build from a wallet's
+	// unspent outputs.
+	BuildRequest struct {
+		// Addresses are the wallet's addresses. Only outputs sent to these
+		// addresses are spent.
+		Addresses  []Address   `json:"addresses"`
+		Recipients []Recipient `json:"recipients"`
+		// ChangeAddress receives any change. It must be one of Addresses. If
+		// it is empty, the first address is used.
+		ChangeAddress types.Address `json:"change_address"`
+		// FeeRate is the miner fee per byte of transaction weight.
+		FeeRate types.Currency `json:"fee_rate"`
+		// Strategy selects the outputs to spend. If it is empty, the largest
+		// outputs are spent first.
+		Strategy SelectionStrategy `json:"strategy"`
+		// Format is the format built by BuildSignedTransaction. It must be
+		// valid at the current height. If it is empty, the format is chosen
+		// by TransactionFormatFor. The other builders ignore it.
+		Format TransactionFormat `json:"format"`
+	}
+
+	// An UnsignedTransaction is a v1 transaction built by BuildTransaction
+	// along with the key index of each signature, in the order expected by
+	// SignTransaction.
+	UnsignedTransaction struct {
+		Selection
+
+		Basis          types.ChainIndex  `json:"basis"`
+		Transaction    types.Transaction `json:"transaction"`
+		SigningIndices []uint64          `json:"signing_indices"`
+	}
+
+	// An UnsignedV2Transaction is a transaction built by BuildV2Transaction
+	// along with the key index of each input, in the order expected by
+	// SignV2Transaction.
+	UnsignedV2Transaction struct {
+		Selection
+
+		Basis          types.ChainIndex    `json:"basis"`
+		Transaction    types.V2Transaction `json:"transaction"`
+		SigningIndices []uint64            `json:"signing_indices"`
+	}
+)
+
+// ErrInsufficientFunds is returned when the wallet's unspent outputs cannot
+// cover the transaction's outputs and fee.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// spendPolicy returns the spend policy that unlocks the address.
+func (a Address) spendPolicy() types.SpendPolicy {
+	if a.Type == AddressTypePublicKey {
+		return types.PolicyPublicKey(a.PublicKey)
+	}
+	return types.SpendPolicy{Type: types.PolicyTypeUnlockConditions(a.UnlockConditions)}
+}
+
+// estimateV2Fee returns the miner fee for txn at feeRate. The fee covers the
+// transaction's full weight, including a placeholder signature for every
+// input, not only its V2TransactionSemantics encoding.
+func estimateV2Fee(cs consensus.State, txn types.V2Transaction, feeRate types.Currency) types.Currency {
+	txn.SiacoinInputs = append([]types.V2SiacoinInput(nil), txn.SiacoinInputs...)
+	for i := range txn.SiacoinInputs {
+		txn.SiacoinInputs[i].SatisfiedPolicy.Signatures = make([]types.Signature, 1)
+	}
+	txn.SiafundInputs = append([]types.V2SiafundInput(nil), txn.SiafundInputs...)
+	for i := range txn.SiafundInputs {
+		txn.SiafundInputs[i].SatisfiedPolicy.Signatures = make([]types.Signature, 1)
+	}
+	return feeRate.Mul64(cs.V2TransactionWeight(txn))
+}
+
+// checkV2Allowed returns an error if v2 transactions are not valid in the
+// block after the state.
+func checkV2Allowed(cs consensus.State) error {
+	return CheckTransactionFormat(cs, FormatV2)
+}
+
+// v2FeeModel returns the fee model for a v2 transaction with the given
+// outputs that spends from the owned addresses.
+func v2FeeModel(cs consensus.State, outputs []types.SiacoinOutput, owned map[types.Address]Address, changeAddress types.Address, feeRate types.Currency) FeeModel {
+	// the fixed cost of a transaction is only counted in Base
+	empty := estimateV2Fee(cs, types.V2Transaction{}, feeRate)
+
+	inputFees := make(map[types.Address]types.Currency)
+	inputFee := func(addr types.Address) types.Currency {
+		if fee, ok := inputFees[addr]; ok {
+			return fee
+		}
+		fee := estimateV2Fee(cs, types.V2Transaction{
+			SiacoinInputs: []types.V2SiacoinInput{{
+				Parent:          types.SiacoinElement{SiacoinOutput: types.SiacoinOutput{Address: addr}},
+				SatisfiedPolicy: types.SatisfiedPolicy{Policy: owned[addr].spendPolicy()},
+			}},
+		}, feeRate).Sub(empty)
+		inputFees[addr] = fee
+		return fee
+	}
+
+	return FeeModel{
+		Base: estimateV2Fee(cs, types.V2Transaction{SiacoinOutputs: outputs}, feeRate),
+		ChangeOutput: estimateV2Fee(cs, types.V2Transaction{
+			SiacoinOutputs: []types.SiacoinOutput{{Address: changeAddress}},
+		}, feeRate).Sub(empty),
+		ChangeSpend: inputFee(changeAddress),
+		Input: func(sco SiacoinOutput) types.Currency {
+			return inputFee(sco.UnlockHash)
+		},
+	}
+}
+
+// A preparedBuild is a validated BuildRequest.
+type preparedBuild struct {
+	owned         map[types.Address]Address
+	changeAddress types.Address
+	outputs       []types.SiacoinOutput
+	amount        types.Currency
+	// elements maps the ID of each spendable output to its element
+	elements  map[types.SiacoinOutputID]types.SiacoinElement
+	spendable []SiacoinOutput
+}
+
+// prepare validates the request and collects the spendable outputs in utxos.
+func (req BuildRequest) prepare(cs consensus.State, utxos []types.SiacoinElement) (preparedBuild, error) {
+	if len(req.Addresses) == 0 {
+		return preparedBuild{}, errors.New("no wallet addresses")
+	} else if len(req.Recipients) == 0 {
+		return preparedBuild{}, errors.New("no recipients")
+	}
+
+	pb := preparedBuild{
+		owned:    make(map[types.Address]Address, len(req.Addresses)),
+		elements: make(map[types.SiacoinOutputID]types.SiacoinElement),
+	}
+	for _, addr := range req.Addresses {
+		if err := addr.validate(); err != nil {
+			return preparedBuild{}, err
+		}
+		pb.owned[addr.Address] = addr
+	}
+
+	pb.changeAddress = req.ChangeAddress
+	if pb.changeAddress == (types.Address{}) {
+		pb.changeAddress = req.Addresses[0].Address
+	} else if _, ok := pb.owned[pb.changeAddress]; !ok {
+		return preparedBuild{}, fmt.Errorf("change address %v is not a wallet address", pb.changeAddress)
+	}
+
+	for _, r := range req.Recipients {
+		if r.Value.IsZero() {
+			return preparedBuild{}, fmt.Errorf("recipient %v has zero value", r.Address)
+		}
+		pb.amount = pb.amount.Add(r.Value)
+		pb.outputs = append(pb.outputs, types.SiacoinOutput{Address: r.Address, Value: r.Value})
+	}
+
+	for _, sce := range utxos {
+		if _, ok := pb.owned[sce.SiacoinOutput.Address]; !ok || sce.MaturityHeight > cs.Index.Height {
+			continue
+		}
+		pb.elements[sce.ID] = sce
+		pb.spendable = append(pb.spendable, SiacoinOutput{
+			OutputID:   sce.ID,
+			UnlockHash: sce.SiacoinOutput.Address,
+			Value:      sce.SiacoinOutput.Value,
+		})
+	}
+	return pb, nil
+}
+
+// BuildV2Transaction selects inputs from utxos to fund the request's
+// recipients and miner fee, adding a change output if necessary. The returned
+// transaction is unsigned.
+func BuildV2Transaction(cs consensus.State, utxos []types.SiacoinElement, req BuildRequest) (UnsignedV2Transaction, error) {
+	if err := checkV2Allowed(cs); err != nil {
+		return UnsignedV2Transaction{}, err
+	}
+	pb, err := req.prepare(cs, utxos)
+	if err != nil {
+		return UnsignedV2Transaction{}, err
+	}
+
+	txn := types.V2Transaction{
+		SiacoinOutputs: pb.outputs,
+	}
+	fees := v2FeeModel(cs, txn.SiacoinOutputs, pb.owned, pb.changeAddress, req.FeeRate)
+	sel, err := SelectCoins(req.Strategy, pb.spendable, pb.amount, fees)
+	if err != nil {
+		return UnsignedV2Transaction{}, err
+	}
+
+	resp := UnsignedV2Transaction{
+		Basis: cs.Index,
+	}
+	for _, sco := range sel.Inputs {
+		addr := pb.owned[sco.UnlockHash]
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{
+			Parent:          pb.elements[sco.OutputID].Copy(),
+			SatisfiedPolicy: types.SatisfiedPolicy{Policy: addr.spendPolicy()},
+		})
+		resp.SigningIndices = append(resp.SigningIndices, addr.Index)
+	}
+	if !sel.Change.IsZero() {
+		if sel.ChangeAddress == (types.Address{}) {
+			sel.ChangeAddress = pb.changeAddress
+		}
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{Address: sel.ChangeAddress, Value: sel.Change})
+	}
+	txn.MinerFee = sel.Fee
+
+	resp.Selection = sel
+	resp.Transaction = txn
+	return resp, nil
+}
+
+// v1FeeModel returns the fee model for a v1 transaction with the given
+// outputs. Currency values are variable length in v1 transactions, so the
+// miner fee and change output are estimated at their maximum size.
+func v1FeeModel(cs consensus.State, outputs []types.SiacoinOutput, owned map[types.Address]Address, changeAddress types.Address, feeRate types.Currency) FeeModel {
+	weightFee := func(txn types.Transaction) types.Currency {
+		return feeRate.Mul64(cs.TransactionWeight(txn))
+	}
+	empty := weightFee(types.Transaction{})
+
+	inputFees := make(map[types.Address]types.Currency)
+	inputFee := func(addr types.Address) types.Currency {
+		if fee, ok := inputFees[addr]; ok {
+			return fee
+		}
+		fee := weightFee(types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{UnlockConditions: owned[addr].UnlockConditions}},
+			Signatures: []types.TransactionSignature{{
+				CoveredFields: types.CoveredFields{WholeTransaction: true},
+				Signature:     make([]byte, 64),
+			}},
+		}).Sub(empty)
+		inputFees[addr] = fee
+		return fee
+	}
+
+	return FeeModel{
+		Base: weightFee(types.Transaction{
+			SiacoinOutputs: outputs,
+			MinerFees:      []types.Currency{types.MaxCurrency},
+		}),
+		ChangeOutput: weightFee(types.Transaction{
+			SiacoinOutputs: []types.SiacoinOutput{{Address: changeAddress, Value: types.MaxCurrency}},
+		}).Sub(empty),
+		ChangeSpend: inputFee(changeAddress),
+		Input: func(sco SiacoinOutput) types.Currency {
+			return inputFee(sco.UnlockHash)
+		},
+	}
+}
+
+// BuildTransaction selects inputs from utxos to fund the request's recipients
+// and miner fee, adding a change output if necessary. The returned v1
+// transaction has an unsigned signature covering the whole transaction for
+// each input.
+func BuildTransaction(cs consensus.State, utxos []types.SiacoinElement, req BuildRequest) (UnsignedTransaction, error) {
+	if err := CheckTransactionFormat(cs, FormatV1); err != nil {
+		return UnsignedTransaction{}, err
+	}
+	pb, err := req.prepare(cs, utxos)
+	if err != nil {
+		return UnsignedTransaction{}, err
+	} else if !pb.owned[pb.changeAddress].v1Spendable() {
+		return UnsignedTransaction{}, fmt.Errorf("change address %v cannot be spent by v1 transactions", pb.changeAddress)
+	}
+	// outputs sent to v2 policy addresses can only be spent by v2
+	// transactions
+	pb.spendable = slices.DeleteFunc(pb.spendable, func(sco SiacoinOutput) bool {
+		return !pb.owned[sco.UnlockHash].v1Spendable()
+	})
+
+	txn := types.Transaction{
+		SiacoinOutputs: pb.outputs,
+	}
+	fees := v1FeeModel(cs, txn.SiacoinOutputs, pb.owned, pb.changeAddress, req.FeeRate)
+	sel, err := SelectCoins(req.Strategy, pb.spendable, pb.amount, fees)
+	if err != nil {
+		return UnsignedTransaction{}, err
+	}
+
+	resp := UnsignedTransaction{
+		Basis: cs.Index,
+	}
+	for _, sco := range sel.Inputs {
+		addr := pb.owned[sco.UnlockHash]
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         sco.OutputID,
+			UnlockConditions: addr.UnlockConditions,
+		})
+		txn.Signatures = append(txn.Signatures, types.TransactionSignature{
+			ParentID:      types.Hash256(sco.OutputID),
+			CoveredFields: types.CoveredFields{WholeTransaction: true},
+		})
+		resp.SigningIndices = append(resp.SigningIndices, addr.Index)
+	}
+	if !sel.Change.IsZero() {
+		if sel.ChangeAddress == (types.Address{}) {
+			sel.ChangeAddress = pb.changeAddress
+		}
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{Address: sel.ChangeAddress, Value: sel.Change})
+	}
+	txn.MinerFees = []types.Currency{sel.Fee}
+
+	resp.Selection = sel
+	resp.Transaction = txn
+	return resp, nil
+}
+
+// SpendableSiacoinElements returns the mature siacoin elements of the
+// addresses that are not spent by a transaction in the transaction pool.
+func SpendableSiacoinElements(w backend.Backend, addresses []types.Address) (types.ChainIndex, []types.SiacoinElement, error) {
+	tip, err := w.ConsensusTip()
+	if err != nil {
+		return types.ChainIndex{}, nil, fmt.Errorf("failed to get consensus tip: %w", err)
+	}
+
+	events, err := w.TPoolEvents()
+	if err != nil {
+		return types.ChainIndex{}, nil, fmt.Errorf("failed to get unconfirmed transactions: %w", err)
+	}
+	spent := make(map[types.SiacoinOutputID]bool)
+	for _, event := range events {
+		switch data := event.Data.(type) {
+		case wallet.EventV1Transaction:
+			for _, sci := range data.Transaction.SiacoinInputs {
+				spent[sci.ParentID] = true
+			}
+		case wallet.EventV2Transaction:
+			for _, sci := range data.SiacoinInputs {
+				spent[sci.Parent.ID] = true
+			}
+		}
+	}
+
+	basis, sces, _, err := unspentElements(w, addresses, false)
+	if err != nil {
+		return types.ChainIndex{}, nil, err
+	}
+	var elements []types.SiacoinElement
+	for _, sce := range sces {
+		if sce.MaturityHeight > tip.Height || spent[sce.ID] {
+			continue
+		}
+		elements = append(elements, sce)
+	}
+	return basis, elements, nil
+}
@@ -0,0 +1,243 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+// testElements returns a siacoin element for each value, sent to the
+// corresponding address.
+func testElements(addresses []Address, values ...types.Currency) []types.SiacoinElement {
+	var sces []types.SiacoinElement
+	for i, v := range values {
+		sces = append(sces, types.SiacoinElement{
+			ID: frand.Entropy256(),
+			SiacoinOutput: types.SiacoinOutput{
+				Address: addresses[i%len(addresses)].Address,
+				Value:   v,
+			},
+		})
+	}
+	return sces
+}
+
+// checkBalanced checks that the transaction's inputs equal its outputs plus
+// the miner fee.
+func checkBalanced(t *testing.T, txn types.V2Transaction) {
+	t.Helper()
+
+	var in, out types.Currency
+	for _, sci := range txn.SiacoinInputs {
+		in = in.Add(sci.Parent.SiacoinOutput.Value)
+	}
+	for _, sco := range txn.SiacoinOutputs {
+		out = out.Add(sco.Value)
+	}
+	if !in.Equals(out.Add(txn.MinerFee)) {
+		t.Fatalf("unbalanced transaction: inputs %v, outputs %v, fee %v", in, out, txn.MinerFee)
+	}
+}
+
+func TestBuildV2Transaction(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(600000)
+	addresses := GenerateAddresses(seed, 0, 3)
+	feeRate := types.Siacoins(1).Div64(1000)
+	recipient := types.Address(frand.Entropy256())
+
+	utxos := testElements(addresses, types.Siacoins(10), types.Siacoins(50), types.Siacoins(20))
+	req := BuildRequest{
+		Addresses:     addresses,
+		Recipients:    []Recipient{{Address: recipient, Value: types.Siacoins(55)}},
+		ChangeAddress: addresses[2].Address,
+		FeeRate:       feeRate,
+	}
+
+	resp, err := BuildV2Transaction(cs, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := resp.Transaction
+	checkBalanced(t, txn)
+
+	// the two largest outputs should be spent
+	if len(txn.SiacoinInputs) != 2 {
+		t.Fatalf("expected 2 inputs, got %d", len(txn.SiacoinInputs))
+	} else if txn.SiacoinInputs[0].Parent.ID != utxos[1].ID || txn.SiacoinInputs[1].Parent.ID != utxos[2].ID {
+		t.Fatal("expected largest outputs to be spent first")
+	} else if resp.SigningIndices[0] != 1 || resp.SigningIndices[1] != 2 {
+		t.Fatalf("unexpected signing indices %v", resp.SigningIndices)
+	}
+
+	if len(txn.SiacoinOutputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %d", len(txn.SiacoinOutputs))
+	} else if change := txn.SiacoinOutputs[1]; change.Address != addresses[2].Address || !change.Value.Equals(resp.Change) {
+		t.Fatalf("unexpected change output %v", change)
+	}
+
+	if err := SignV2Transaction(cs, seed, &txn, resp.SigningIndices); err != nil {
+		t.Fatal(err)
+	}
+	// the signed transaction must be covered by the estimated fee
+	if expected := feeRate.Mul64(cs.V2TransactionWeight(txn)); !txn.MinerFee.Equals(expected) {
+		t.Fatalf("expected fee %v, got %v", expected, txn.MinerFee)
+	}
+
+	// sending almost everything spends every output
+	var total types.Currency
+	for _, sce := range utxos {
+		total = total.Add(sce.SiacoinOutput.Value)
+	}
+	req.Recipients[0].Value = total.Sub(types.Siacoins(1))
+	resp, err = BuildV2Transaction(cs, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Transaction.SiacoinInputs) != 3 || resp.Change.IsZero() {
+		t.Fatal("expected all inputs to be spent with change")
+	}
+	checkBalanced(t, resp.Transaction)
+
+	// leave slightly less than the fee with a change output as excess. The
+	// transaction is still funded without the change output.
+	excess := resp.Fee.Sub(feeRate)
+	req.Recipients[0].Value = total.Sub(excess)
+	resp, err = BuildV2Transaction(cs, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Transaction.SiacoinOutputs) != 1 || !resp.Change.IsZero() {
+		t.Fatal("expected no change output")
+	} else if !resp.Fee.Equals(excess) {
+		t.Fatalf("expected excess %v to be added to the fee, got %v", excess, resp.Fee)
+	}
+	checkBalanced(t, resp.Transaction)
+
+	req.Recipients[0].Value = total
+	if _, err := BuildV2Transaction(cs, utxos, req); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient funds, got %v", err)
+	}
+
+	// the privacy strategy returns change to the address being spent from
+	req.Recipients[0].Value = types.Siacoins(15)
+	req.Strategy = SelectPrivacy
+	resp, err = BuildV2Transaction(cs, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(resp.Transaction.SiacoinInputs) != 1 || resp.Transaction.SiacoinInputs[0].Parent.ID != utxos[1].ID {
+		t.Fatal("expected only the largest output to be spent")
+	} else if change := resp.Transaction.SiacoinOutputs[1]; change.Address != addresses[1].Address {
+		t.Fatalf("expected change to %v, got %v", addresses[1].Address, change.Address)
+	}
+	checkBalanced(t, resp.Transaction)
+	req.Strategy = ""
+
+	req.Recipients[0].Value = types.Siacoins(1)
+	req.ChangeAddress = recipient
+	if _, err := BuildV2Transaction(cs, utxos, req); err == nil {
+		t.Fatal("expected change address error")
+	}
+
+	req.ChangeAddress = types.Address{}
+	if _, err := BuildV2Transaction(testState(cs.Network.HardforkV2.AllowHeight-2), utxos, req); err == nil {
+		t.Fatal("expected v2 not allowed error")
+	}
+}
+
+func TestV2FeeModel(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(600000)
+	addresses := GenerateAddresses(seed, 0, 2)
+	owned := map[types.Address]Address{addresses[0].Address: addresses[0], addresses[1].Address: addresses[1]}
+	feeRate := types.Siacoins(1).Div64(1000)
+	outputs := []types.SiacoinOutput{{Address: types.VoidAddress, Value: types.Siacoins(1)}}
+
+	// the parts of the model add up to the fee of the whole transaction
+	fees := v2FeeModel(cs, outputs, owned, addresses[1].Address, feeRate)
+	txn := types.V2Transaction{
+		SiacoinOutputs: append(outputs, types.SiacoinOutput{Address: addresses[1].Address}),
+	}
+	expected := fees.Base.Add(fees.ChangeOutput)
+	for _, addr := range addresses {
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{
+			Parent:          types.SiacoinElement{SiacoinOutput: types.SiacoinOutput{Address: addr.Address}},
+			SatisfiedPolicy: types.SatisfiedPolicy{Policy: addr.spendPolicy()},
+		})
+		expected = expected.Add(fees.Input(SiacoinOutput{UnlockHash: addr.Address}))
+	}
+	if fee := estimateV2Fee(cs, txn, feeRate); !fee.Equals(expected) {
+		t.Fatalf("expected fee %v, got %v", fee, expected)
+	}
+}
+
+func TestSpendableSiacoinElements(t *testing.T) {
+	seed := testSeed(t)
+	b := backend.NewMemory(testState(1000))
+	addresses := GenerateAddresses(seed, 0, 2)
+
+	utxos := testElements(addresses, types.Siacoins(1), types.Siacoins(2), types.Siacoins(3))
+	utxos[2].MaturityHeight = 1001
+	b.AddSiacoinElements(utxos...)
+	b.AddUnconfirmedEvents(wallet.Event{
+		ID: frand.Entropy256(),
+		Data: wallet.EventV2Transaction{
+			SiacoinInputs: []types.V2SiacoinInput{{Parent: utxos[0]}},
+		},
+	})
+
+	basis, elements, err := SpendableSiacoinElements(b, []types.Address{addresses[0].Address, addresses[1].Address})
+	if err != nil {
+		t.Fatal(err)
+	} else if basis.Height != 1000 {
+		t.Fatalf("expected basis height 1000, got %d", basis.Height)
+	} else if len(elements) != 1 || elements[0].ID != utxos[1].ID {
+		t.Fatalf("expected only the unspent mature output, got %d elements", len(elements))
+	}
+}
+
+func TestBuildPolicyAddressTransaction(t *testing.T) {
+	seed := testSeed(t)
+	addresses := []Address{GenerateAddress(seed, 0), GeneratePolicyAddress(seed, 1)}
+	feeRate := types.Siacoins(1).Div64(1000)
+
+	cs, utxos := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: addresses[0].Address, Value: types.Siacoins(10)},
+		types.SiacoinOutput{Address: addresses[1].Address, Value: types.Siacoins(20)},
+	)
+	req := BuildRequest{
+		Addresses:  addresses,
+		Recipients: []Recipient{{Address: frand.Entropy256(), Value: types.Siacoins(25)}},
+		FeeRate:    feeRate,
+	}
+
+	// both address types are spent with their own policy
+	resp, err := BuildV2Transaction(cs, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := resp.Transaction.DeepCopy()
+	if err := SignV2Transaction(cs, seed, &txn, resp.SigningIndices); err != nil {
+		t.Fatal(err)
+	} else if res := ValidateV2Transaction(cs, txn, utxos, nil, feeRate); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+
+	// v1 transactions cannot spend the policy address's output
+	v1State := testState(500000)
+	if _, err := BuildTransaction(v1State, utxos, req); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient funds, got %v", err)
+	}
+	req.Recipients[0].Value = types.Siacoins(5)
+	if resp, err := BuildTransaction(v1State, utxos, req); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Transaction.SiacoinInputs) != 1 || resp.SigningIndices[0] != 0 {
+		t.Fatal("expected only the v1 address to be spent")
+	}
+	req.ChangeAddress = addresses[1].Address
+	if _, err := BuildTransaction(v1State, utxos, req); err == nil {
+		t.Fatal("expected change address error")
+	}
+}
@@ -0,0 +1,222 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.sia.tech/core/types"
+)
+
+// A SelectionStrategy determines which unspent outputs fund a transaction.
+type SelectionStrategy string
+
+// Supported coin selection strategies.
+const (
+	// SelectLargestFirst spends the largest outputs first, minimizing the
+	// number of inputs and the fee.
+	SelectLargestFirst SelectionStrategy = "largest_first"
+	// SelectSmallestFirst spends the smallest outputs first to clean up
+	// dust.
+	SelectSmallestFirst SelectionStrategy = "smallest_first"
+	// SelectExactMatch uses branch-and-bound to find a set of outputs that
+	// funds the transaction without a change output.
+	SelectExactMatch SelectionStrategy = "exact_match"
+	// SelectPrivacy spends outputs from a single address and returns the
+	// change to it so that no addresses are linked by the transaction.
+	SelectPrivacy SelectionStrategy = "privacy"
+)
+
+// maxExactMatchTries is the maximum number of branches searched by
+// SelectExactMatch.
+const maxExactMatchTries = 100000
+
+type (
+	// A FeeModel describes the fee contributed by each part of a
+	// transaction. v2 transaction weight is the sum of the weight of its
+	// inputs and outputs, so the fee of any selection can be computed exactly.
+	FeeModel struct {
+		// Base is the fee for the transaction without inputs or a change
+		// output.
+		Base types.Currency
+		// ChangeOutput is the fee for adding a change output.
+		ChangeOutput types.Currency
+		// ChangeSpend is the fee for spending the change output in a
+		// future transaction.
+		ChangeSpend types.Currency
+		// Input returns the fee for spending an output.
+		Input func(SiacoinOutput) types.Currency
+	}
+
+	// A Selection is the set of outputs chosen to fund a transaction.
+	Selection struct {
+		Inputs []SiacoinOutput `json:"inputs"`
+		Change types.Currency  `json:"change"`
+		Fee    types.Currency  `json:"fee"`
+		// ChangeAddress is set if the strategy requires the change to be
+		// sent to a specific address.
+		ChangeAddress types.Address `json:"change_address,omitempty"`
+	}
+)
+
+// ErrNoExactMatch is returned by SelectExactMatch when no set of outputs
+// funds the transaction without a change output.
+var ErrNoExactMatch = errors.New("no exact match found")
+
+// accumulate adds outputs in order until the amount and fee are covered.
+func accumulate(utxos []SiacoinOutput, amount types.Currency, fees FeeModel) (Selection, bool) {
+	var sel Selection
+	var inputSum types.Currency
+	fee := fees.Base
+	for _, sco := range utxos {
+		sel.Inputs = append(sel.Inputs, sco)
+		inputSum = inputSum.Add(sco.Value)
+		fee = fee.Add(fees.Input(sco))
+
+		if withChange := amount.Add(fee).Add(fees.ChangeOutput); inputSum.Cmp(withChange) > 0 {
+			sel.Change = inputSum.Sub(withChange)
+			sel.Fee = fee.Add(fees.ChangeOutput)
+			return sel, true
+		} else if inputSum.Cmp(amount.Add(fee)) >= 0 {
+			// without a change output, any excess is added to the fee
+			sel.Fee = inputSum.Sub(amount)
+			return sel, true
+		}
+	}
+	return sel, false
+}
+
+// selectExactMatch searches for a set of outputs whose value, less the fee to
+// spend them, is between the target and the target plus the cost of a change
+// output.
+func selectExactMatch(utxos []SiacoinOutput, amount types.Currency, fees FeeModel) (Selection, error) {
+	type candidate struct {
+		SiacoinOutput
+		effective types.Currency
+	}
+
+	var candidates []candidate
+	var available types.Currency
+	for _, sco := range utxos {
+		fee := fees.Input(sco)
+		if sco.Value.Cmp(fee) <= 0 {
+			continue // not worth spending
+		}
+		c := candidate{sco, sco.Value.Sub(fee)}
+		candidates = append(candidates, c)
+		available = available.Add(c.effective)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].effective.Cmp(candidates[j].effective) > 0
+	})
+
+	target := amount.Add(fees.Base)
+	upper := target.Add(fees.ChangeOutput).Add(fees.ChangeSpend)
+	if available.Cmp(target) < 0 {
+		return Selection{}, fmt.Errorf("%w: have %v, need %v plus fees", ErrInsufficientFunds, available, amount)
+	}
+
+	var tries int
+	var selected, best []int
+	var search func(i int, sum, remaining types.Currency) bool
+	search = func(i int, sum, remaining types.Currency) bool {
+		tries++
+		switch {
+		case sum.Cmp(upper) > 0:
+			return false // overshot the window
+		case sum.Cmp(target) >= 0:
+			best = append(best[:0], selected...)
+			return true
+		case i >= len(candidates), tries > maxExactMatchTries, sum.Add(remaining).Cmp(target) < 0:
+			return false // not enough value left on this branch
+		}
+
+		remaining = remaining.Sub(candidates[i].effective)
+		// include the candidate
+		selected = append(selected, i)
+		if search(i+1, sum.Add(candidates[i].effective), remaining) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+		// exclude the candidate
+		return search(i+1, sum, remaining)
+	}
+	if !search(0, types.ZeroCurrency, available) {
+		return Selection{}, ErrNoExactMatch
+	}
+
+	var sel Selection
+	var inputSum types.Currency
+	for _, i := range best {
+		sel.Inputs = append(sel.Inputs, candidates[i].SiacoinOutput)
+		inputSum = inputSum.Add(candidates[i].Value)
+	}
+	sel.Fee = inputSum.Sub(amount)
+	return sel, nil
+}
+
+// selectPrivacy funds the transaction from a single address. The address
+// that can fund the transaction with the fewest inputs is used.
+func selectPrivacy(utxos []SiacoinOutput, amount types.Currency, fees FeeModel) (Selection, error) {
+	byAddress := make(map[types.Address][]SiacoinOutput)
+	var addresses []types.Address
+	for _, sco := range utxos {
+		if _, ok := byAddress[sco.UnlockHash]; !ok {
+			addresses = append(addresses, sco.UnlockHash)
+		}
+		byAddress[sco.UnlockHash] = append(byAddress[sco.UnlockHash], sco)
+	}
+
+	var best Selection
+	var found bool
+	for _, addr := range addresses {
+		outputs := byAddress[addr]
+		sort.Slice(outputs, func(i, j int) bool {
+			return outputs[i].Value.Cmp(outputs[j].Value) > 0
+		})
+		sel, ok := accumulate(outputs, amount, fees)
+		if !ok {
+			continue
+		} else if !found || len(sel.Inputs) < len(best.Inputs) || (len(sel.Inputs) == len(best.Inputs) && sel.Fee.Cmp(best.Fee) < 0) {
+			sel.ChangeAddress = addr
+			best, found = sel, true
+		}
+	}
+	if !found {
+		return Selection{}, fmt.Errorf("%w: no single address can fund %v plus fees", ErrInsufficientFunds, amount)
+	}
+	return best, nil
+}
+
+// SelectCoins chooses outputs from utxos to fund amount plus the fee
+// described by fees.
+func SelectCoins(strategy SelectionStrategy, utxos []SiacoinOutput, amount types.Currency, fees FeeModel) (Selection, error) {
+	utxos = append([]SiacoinOutput(nil), utxos...)
+
+	switch strategy {
+	case SelectLargestFirst, "":
+		sort.Slice(utxos, func(i, j int) bool {
+			return utxos[i].Value.Cmp(utxos[j].Value) > 0
+		})
+	case SelectSmallestFirst:
+		sort.Slice(utxos, func(i, j int) bool {
+			return utxos[i].Value.Cmp(utxos[j].Value) < 0
+		})
+	case SelectExactMatch:
+		return selectExactMatch(utxos, amount, fees)
+	case SelectPrivacy:
+		return selectPrivacy(utxos, amount, fees)
+	default:
+		return Selection{}, fmt.Errorf("unknown selection strategy %q", strategy)
+	}
+
+	sel, ok := accumulate(utxos, amount, fees)
+	if !ok {
+		var inputSum types.Currency
+		for _, sco := range utxos {
+			inputSum = inputSum.Add(sco.Value)
+		}
+		return Selection{}, fmt.Errorf("%w: have %v, need %v plus fees", ErrInsufficientFunds, inputSum, amount)
+	}
+	return sel, nil
+}
@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func testOutputs(addr types.Address, values ...uint32) []SiacoinOutput {
+	var outputs []SiacoinOutput
+	for _, v := range values {
+		outputs = append(outputs, SiacoinOutput{
+			OutputID:   frand.Entropy256(),
+			UnlockHash: addr,
+			Value:      types.Siacoins(v),
+		})
+	}
+	return outputs
+}
+
+// checkSelection checks that the selection's inputs cover the amount, fee and
+// change.
+func checkSelection(t *testing.T, sel Selection, amount types.Currency) {
+	t.Helper()
+
+	var in types.Currency
+	for _, sco := range sel.Inputs {
+		in = in.Add(sco.Value)
+	}
+	if !in.Equals(amount.Add(sel.Fee).Add(sel.Change)) {
+		t.Fatalf("unbalanced selection: inputs %v, amount %v, fee %v, change %v", in, amount, sel.Fee, sel.Change)
+	}
+}
+
+func TestSelectCoins(t *testing.T) {
+	fees := FeeModel{
+		Base:         types.Siacoins(1),
+		ChangeOutput: types.Siacoins(1),
+		ChangeSpend:  types.Siacoins(1),
+		Input:        func(SiacoinOutput) types.Currency { return types.Siacoins(1) },
+	}
+	addr := types.Address(frand.Entropy256())
+	utxos := testOutputs(addr, 5, 40, 10, 20, 1)
+
+	tests := []struct {
+		strategy SelectionStrategy
+		amount   uint32
+		inputs   []types.Currency
+		change   types.Currency
+	}{
+		// 40 - 1 base - 1 input - 1 change output
+		{"", 30, []types.Currency{types.Siacoins(40)}, types.Siacoins(7)},
+		{SelectLargestFirst, 50, []types.Currency{types.Siacoins(40), types.Siacoins(20)}, types.Siacoins(6)},
+		// the 1 SC output costs as much to spend as it is worth
+		{SelectSmallestFirst, 10, []types.Currency{types.Siacoins(1), types.Siacoins(5), types.Siacoins(10)}, types.Siacoins(1)},
+		// 20 + 10 - 1 base - 2 inputs = 27 with no change output
+		{SelectExactMatch, 27, []types.Currency{types.Siacoins(20), types.Siacoins(10)}, types.ZeroCurrency},
+	}
+	for _, test := range tests {
+		amount := types.Siacoins(test.amount)
+		sel, err := SelectCoins(test.strategy, utxos, amount, fees)
+		if err != nil {
+			t.Fatalf("%q: %v", test.strategy, err)
+		}
+		checkSelection(t, sel, amount)
+		if len(sel.Inputs) != len(test.inputs) {
+			t.Fatalf("%q: expected %d inputs, got %d", test.strategy, len(test.inputs), len(sel.Inputs))
+		}
+		for i := range sel.Inputs {
+			if !sel.Inputs[i].Value.Equals(test.inputs[i]) {
+				t.Fatalf("%q: expected input %d to be %v, got %v", test.strategy, i, test.inputs[i], sel.Inputs[i].Value)
+			}
+		}
+		if !sel.Change.Equals(test.change) {
+			t.Fatalf("%q: expected change %v, got %v", test.strategy, test.change, sel.Change)
+		}
+	}
+
+	// exact match falls back to an error rather than creating change
+	if _, err := SelectCoins(SelectExactMatch, testOutputs(addr, 100), types.Siacoins(10), fees); !errors.Is(err, ErrNoExactMatch) {
+		t.Fatalf("expected no exact match, got %v", err)
+	}
+
+	for _, strategy := range []SelectionStrategy{SelectLargestFirst, SelectSmallestFirst, SelectExactMatch, SelectPrivacy} {
+		if _, err := SelectCoins(strategy, utxos, types.Siacoins(100), fees); !errors.Is(err, ErrInsufficientFunds) {
+			t.Fatalf("%q: expected insufficient funds, got %v", strategy, err)
+		}
+	}
+
+	if _, err := SelectCoins("foo", utxos, types.Siacoins(1), fees); err == nil {
+		t.Fatal("expected unknown strategy error")
+	}
+}
+
+func TestSelectPrivacy(t *testing.T) {
+	fees := FeeModel{
+		Input: func(SiacoinOutput) types.Currency { return types.Siacoins(1) },
+	}
+	a, b := types.Address(frand.Entropy256()), types.Address(frand.Entropy256())
+	utxos := append(testOutputs(a, 10, 10, 10), testOutputs(b, 25, 1)...)
+
+	// b can fund 20 SC with a single input
+	sel, err := SelectCoins(SelectPrivacy, utxos, types.Siacoins(20), fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSelection(t, sel, types.Siacoins(20))
+	if len(sel.Inputs) != 1 || sel.ChangeAddress != b {
+		t.Fatalf("expected a single input from %v, got %d inputs with change to %v", b, len(sel.Inputs), sel.ChangeAddress)
+	}
+
+	// only a can fund 27 SC, even though the wallet holds enough in total
+	// to fund it with fewer inputs across both addresses
+	sel, err = SelectCoins(SelectPrivacy, utxos, types.Siacoins(27), fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSelection(t, sel, types.Siacoins(27))
+	for _, sco := range sel.Inputs {
+		if sco.UnlockHash != a {
+			t.Fatal("expected inputs from a single address")
+		}
+	}
+	if sel.ChangeAddress != a {
+		t.Fatalf("expected change to %v, got %v", a, sel.ChangeAddress)
+	}
+}
@@ -0,0 +1,183 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+// defaultConsolidationInputs is the number of inputs merged by each
+// consolidation transaction if the request does not specify one.
+const defaultConsolidationInputs = 100
+
+type (
+	// A ConsolidationRequest describes a set of transactions that merge a
+	// wallet's unspent outputs.
+	ConsolidationRequest struct {
+		// Addresses are the wallet's addresses. Only outputs sent to these
+		// addresses are consolidated.
+		Addresses []Address `json:"addresses"`
+		// Target receives the consolidated outputs. It must be one of the
+		// wallet's addresses. If it is empty, the first address is used.
+		Target types.Address `json:"target"`
+		// MaxInputs is the maximum number of inputs in each transaction. If it
+		// is zero, 100 inputs are used.
+		MaxInputs int `json:"max_inputs"`
+		// FeeRate is the miner fee per byte of transaction weight.
+		FeeRate types.Currency `json:"fee_rate"`
+	}
+
+	// A ConsolidationPlan is the set of unsigned transactions that
+	// consolidate a wallet's outputs.
+	ConsolidationPlan struct {
+		Transactions []UnsignedV2Transaction `json:"transactions"`
+		// Skipped are the outputs that are worth less than the fee to spend
+		// them.
+		Skipped []SiacoinOutput `json:"skipped"`
+		// Remaining are outputs worth more than the fee to spend them that
+		// are still left out: an output that could not be paired with
+		// another output, such as a wallet's only spendable output, or the
+		// outputs of a batch worth too little to pay the fee of its
+		// transaction.
+		Remaining []SiacoinOutput `json:"remaining"`
+		// Fee is the total miner fee of all transactions.
+		Fee types.Currency `json:"fee"`
+	}
+)
+
+// PlanConsolidation groups the wallet's spendable outputs into transactions
+// of up to MaxInputs inputs, each sending the inputs' value less the miner
+// fee to a single output at the target address. Outputs worth less than the
+// fee to spend them are skipped, and spendable outputs that cannot be put in
+// a transaction are reported as remaining, so every output is accounted for.
+// The transactions spend disjoint outputs, so they can be signed and
+// broadcast independently.
+func PlanConsolidation(cs consensus.State, utxos []types.SiacoinElement, req ConsolidationRequest) (ConsolidationPlan, error) {
+	if err := checkV2Allowed(cs); err != nil {
+		return ConsolidationPlan{}, err
+	} else if len(req.Addresses) == 0 {
+		return ConsolidationPlan{}, errors.New("no wallet addresses")
+	} else if req.MaxInputs < 0 {
+		return ConsolidationPlan{}, errors.New("max inputs must be positive")
+	}
+
+	maxInputs := req.MaxInputs
+	if maxInputs == 0 {
+		maxInputs = defaultConsolidationInputs
+	} else if maxInputs < 2 {
+		return ConsolidationPlan{}, errors.New("at least 2 inputs are required to consolidate outputs")
+	}
+
+	owned := make(map[types.Address]Address, len(req.Addresses))
+	for _, addr := range req.Addresses {
+		if err := addr.validate(); err != nil {
+			return ConsolidationPlan{}, err
+		}
+		owned[addr.Address] = addr
+	}
+
+	target := req.Target
+	if target == (types.Address{}) {
+		target = req.Addresses[0].Address
+	} else if _, ok := owned[target]; !ok {
+		return ConsolidationPlan{}, errors.New("target is not a wallet address")
+	}
+
+	// the output value does not change the weight of the transaction
+	fees := v2FeeModel(cs, []types.SiacoinOutput{{Address: target}}, owned, target, req.FeeRate)
+
+	var plan ConsolidationPlan
+	var spendable []types.SiacoinElement
+	for _, sce := range utxos {
+		if _, ok := owned[sce.SiacoinOutput.Address]; !ok || sce.MaturityHeight > cs.Index.Height {
+			continue
+		}
+
+		sco := SiacoinOutput{
+			OutputID:   sce.ID,
+			UnlockHash: sce.SiacoinOutput.Address,
+			Value:      sce.SiacoinOutput.Value,
+		}
+		if sco.Value.Cmp(fees.Input(sco)) <= 0 {
+			plan.Skipped = append(plan.Skipped, sco)
+			continue
+		}
+		spendable = append(spendable, sce)
+	}
+	sort.Slice(spendable, func(i, j int) bool {
+		return spendable[i].SiacoinOutput.Value.Cmp(spendable[j].SiacoinOutput.Value) > 0
+	})
+
+	// split the outputs into batches of at least 2 inputs. If the last
+	// batch would hold a single output, it takes one from the previous batch
+	// instead, which is only possible if that batch keeps 2 inputs.
+	var bounds []int
+	for i := 0; i < len(spendable); i += maxInputs {
+		bounds = append(bounds, i)
+	}
+	if n := len(bounds); n > 0 && len(spendable)-bounds[n-1] == 1 {
+		if n > 1 && maxInputs > 2 {
+			bounds[n-1]--
+		} else {
+			last := spendable[len(spendable)-1]
+			plan.Remaining = append(plan.Remaining, SiacoinOutput{
+				OutputID:   last.ID,
+				UnlockHash: last.SiacoinOutput.Address,
+				Value:      last.SiacoinOutput.Value,
+			})
+			spendable = spendable[:len(spendable)-1]
+			bounds = bounds[:n-1]
+		}
+	}
+	bounds = append(bounds, len(spendable))
+
+	for j, i := range bounds[:len(bounds)-1] {
+		batch := spendable[i:bounds[j+1]]
+
+		resp := UnsignedV2Transaction{
+			Basis: cs.Index,
+		}
+		txn := types.V2Transaction{
+			MinerFee: fees.Base,
+		}
+		var inputSum types.Currency
+		for _, sce := range batch {
+			addr := owned[sce.SiacoinOutput.Address]
+			sco := SiacoinOutput{
+				OutputID:   sce.ID,
+				UnlockHash: sce.SiacoinOutput.Address,
+				Value:      sce.SiacoinOutput.Value,
+			}
+			txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{
+				Parent:          sce.Copy(),
+				SatisfiedPolicy: types.SatisfiedPolicy{Policy: addr.spendPolicy()},
+			})
+			txn.MinerFee = txn.MinerFee.Add(fees.Input(sco))
+			inputSum = inputSum.Add(sco.Value)
+			resp.Inputs = append(resp.Inputs, sco)
+			resp.SigningIndices = append(resp.SigningIndices, addr.Index)
+		}
+		if inputSum.Cmp(txn.MinerFee) <= 0 {
+			// the outputs are sorted by value, so no later batch can cover
+			// the base fee either. Each output is still worth more than its
+			// own fee, so they remain rather than being skipped.
+			for _, sce := range spendable[i:] {
+				plan.Remaining = append(plan.Remaining, SiacoinOutput{
+					OutputID:   sce.ID,
+					UnlockHash: sce.SiacoinOutput.Address,
+					Value:      sce.SiacoinOutput.Value,
+				})
+			}
+			break
+		}
+		txn.SiacoinOutputs = []types.SiacoinOutput{{Address: target, Value: inputSum.Sub(txn.MinerFee)}}
+
+		resp.Fee = txn.MinerFee
+		resp.Transaction = txn
+		plan.Transactions = append(plan.Transactions, resp)
+		plan.Fee = plan.Fee.Add(txn.MinerFee)
+	}
+	return plan, nil
+}
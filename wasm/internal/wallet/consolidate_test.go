@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+)
+
+func TestPlanConsolidation(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(600000)
+	addresses := GenerateAddresses(seed, 0, 3)
+	feeRate := types.Siacoins(1).Div64(1000)
+
+	values := make([]types.Currency, 0, 11)
+	for i := 0; i < 10; i++ {
+		values = append(values, types.Siacoins(uint32(i+1)))
+	}
+	// dust worth less than the fee to spend it
+	values = append(values, types.NewCurrency64(1))
+	utxos := testElements(addresses, values...)
+
+	plan, err := PlanConsolidation(cs, utxos, ConsolidationRequest{
+		Addresses: addresses,
+		Target:    addresses[1].Address,
+		MaxInputs: 4,
+		FeeRate:   feeRate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Skipped) != 1 || plan.Skipped[0].OutputID != utxos[10].ID {
+		t.Fatalf("expected the dust output to be skipped, got %v", plan.Skipped)
+	} else if len(plan.Transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(plan.Transactions))
+	}
+
+	var fee types.Currency
+	for i, resp := range plan.Transactions {
+		txn := resp.Transaction
+		checkBalanced(t, txn)
+		if expected := min(4, 10-4*i); len(txn.SiacoinInputs) != expected {
+			t.Fatalf("expected %d inputs, got %d", expected, len(txn.SiacoinInputs))
+		} else if len(txn.SiacoinOutputs) != 1 || txn.SiacoinOutputs[0].Address != addresses[1].Address {
+			t.Fatal("expected a single output to the target address")
+		}
+		for j, sci := range txn.SiacoinInputs {
+			if expected := addresses[resp.SigningIndices[j]].Address; sci.Parent.SiacoinOutput.Address != expected {
+				t.Fatalf("input %d signing index does not match its address", j)
+			}
+		}
+
+		if err := SignV2Transaction(cs, seed, &txn, resp.SigningIndices); err != nil {
+			t.Fatal(err)
+		} else if expected := feeRate.Mul64(cs.V2TransactionWeight(txn)); !txn.MinerFee.Equals(expected) {
+			t.Fatalf("expected fee %v, got %v", expected, txn.MinerFee)
+		}
+		fee = fee.Add(txn.MinerFee)
+	}
+	if !fee.Equals(plan.Fee) {
+		t.Fatalf("expected total fee %v, got %v", fee, plan.Fee)
+	}
+
+	// with maxInputs+1 outputs, the last batch takes an output from the
+	// previous one instead of leaving an output behind
+	plan, err = PlanConsolidation(cs, utxos[:5], ConsolidationRequest{Addresses: addresses, MaxInputs: 4, FeeRate: feeRate})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(plan.Transactions))
+	} else if n, m := len(plan.Transactions[0].Inputs), len(plan.Transactions[1].Inputs); n != 3 || m != 2 {
+		t.Fatalf("expected 3 and 2 inputs, got %d and %d", n, m)
+	} else if len(plan.Remaining) != 0 {
+		t.Fatalf("expected no remaining outputs, got %v", plan.Remaining)
+	}
+
+	// with 2 inputs per transaction the odd output cannot be paired, so it
+	// is reported as remaining
+	plan, err = PlanConsolidation(cs, utxos[:5], ConsolidationRequest{Addresses: addresses, MaxInputs: 2, FeeRate: feeRate})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(plan.Transactions))
+	} else if len(plan.Remaining) != 1 {
+		t.Fatalf("expected 1 remaining output, got %v", plan.Remaining)
+	}
+
+	// a single spendable output is already consolidated
+	plan, err = PlanConsolidation(cs, utxos[:1], ConsolidationRequest{Addresses: addresses, FeeRate: feeRate})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Transactions) != 0 {
+		t.Fatalf("expected no transactions, got %d", len(plan.Transactions))
+	} else if len(plan.Remaining) != 1 || plan.Remaining[0].OutputID != utxos[0].ID {
+		t.Fatalf("expected the output to remain, got %v", plan.Remaining)
+	}
+
+	// outputs barely worth their own fee cannot pay the fee of the output
+	// they are consolidated into, so they remain instead of being skipped
+	fees := v2FeeModel(cs, []types.SiacoinOutput{{Address: addresses[0].Address}}, map[types.Address]Address{addresses[0].Address: addresses[0]}, addresses[0].Address, feeRate)
+	small := fees.Input(SiacoinOutput{UnlockHash: addresses[0].Address}).Add(types.NewCurrency64(1))
+	plan, err = PlanConsolidation(cs, testElements(addresses[:1], small, small), ConsolidationRequest{Addresses: addresses, FeeRate: feeRate})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Transactions) != 0 || len(plan.Skipped) != 0 {
+		t.Fatalf("expected no transactions or skipped outputs, got %d and %d", len(plan.Transactions), len(plan.Skipped))
+	} else if len(plan.Remaining) != 2 {
+		t.Fatalf("expected 2 remaining outputs, got %v", plan.Remaining)
+	}
+
+	if _, err := PlanConsolidation(cs, utxos, ConsolidationRequest{Addresses: addresses, MaxInputs: 1}); err == nil {
+		t.Fatal("expected max inputs error")
+	} else if _, err := PlanConsolidation(cs, utxos, ConsolidationRequest{Addresses: addresses, Target: types.Address{1}}); err == nil {
+		t.Fatal("expected target address error")
+	}
+}
@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+)
+
+// ErrNotConvertible is returned when a phrase cannot be converted to the
+// requested format. A 12-word phrase encodes 128 bits of entropy and its seed
+// is the BLAKE2b hash of that entropy, so no 12-word phrase exists for an
+// arbitrary seed.
+var ErrNotConvertible = errors.New("a 12-word phrase cannot be derived from an arbitrary seed")
+
+// conversionIndices are the key indices whose addresses must match before a
+// converted phrase is returned.
+var conversionIndices = []uint64{0, 1, 2, 100, 1000, math.MaxUint64}
+
+// A PhraseConversion is a recovery phrase converted to another format.
+type PhraseConversion struct {
+	Phrase   string `json:"phrase"`
+	Format   string `json:"format"`
+	Language string `json:"language,omitempty"`
+	// SourceFormat is the format of the original phrase.
+	SourceFormat string `json:"source_format"`
+	// VerifiedIndices are the key indices whose v1 and v2 addresses were
+	// checked to match between the phrases.
+	VerifiedIndices []uint64 `json:"verified_indices"`
+}
+
+// verifyConversion checks that two phrases derive the same addresses.
+func verifyConversion(original, converted string) error {
+	var a, b [32]byte
+	defer clear(a[:])
+	defer clear(b[:])
+	if err := PhraseToSeed(original, &a); err != nil {
+		return fmt.Errorf("failed to derive original seed: %w", err)
+	} else if err := PhraseToSeed(converted, &b); err != nil {
+		return fmt.Errorf("failed to derive converted seed: %w", err)
+	}
+
+	for _, i := range conversionIndices {
+		if GenerateAddress(&a, i).Address != GenerateAddress(&b, i).Address {
+			return fmt.Errorf("address %d does not match", i)
+		} else if GeneratePolicyAddress(&a, i).Address != GeneratePolicyAddress(&b, i).Address {
+			return fmt.Errorf("policy address %d does not match", i)
+		}
+	}
+	return nil
+}
+
+// ConvertPhrase converts a 12-word or siad recovery phrase to the siad phrase
+// of the same seed in the given language. seedType is the requested format.
+// Only SeedTypeSia can be derived from any phrase; SeedTypeWalrus is only
+// accepted for a phrase that is already a 12-word phrase and otherwise
+// returns ErrNotConvertible. The converted phrase is checked to derive the
+// same addresses as the original before it is returned.
+func ConvertPhrase(phrase, seedType string, lang siad.Language) (PhraseConversion, error) {
+	words := splitPhrase(phrase)
+	dict, ok := detectFormat(words)
+	if !ok {
+		return PhraseConversion{}, fmt.Errorf("invalid seed phrase length: %d words", len(words))
+	}
+
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := PhraseToSeed(phrase, &seed); err != nil {
+		return PhraseConversion{}, err
+	}
+
+	conv := PhraseConversion{
+		Format:          seedType,
+		SourceFormat:    dict.seedType,
+		VerifiedIndices: conversionIndices,
+	}
+	switch {
+	case seedType == SeedTypeWalrus && dict.seedType == SeedTypeWalrus:
+		normalized, _ := dict.normalizeWords(words)
+		conv.Phrase = strings.Join(normalized, " ")
+	case seedType == SeedTypeWalrus:
+		return PhraseConversion{}, ErrNotConvertible
+	case seedType == SeedTypeSia:
+		converted, err := siad.SeedToPhraseLanguage(&seed, lang)
+		if err != nil {
+			return PhraseConversion{}, err
+		}
+		conv.Phrase, conv.Language = converted, string(lang)
+	default:
+		return PhraseConversion{}, fmt.Errorf("unknown seed type: %q", seedType)
+	}
+
+	if err := verifyConversion(phrase, conv.Phrase); err != nil {
+		return PhraseConversion{}, fmt.Errorf("converted phrase does not match: %w", err)
+	}
+	return conv, nil
+}
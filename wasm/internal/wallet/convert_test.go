@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+)
+
+func TestConvertPhrase(t *testing.T) {
+	for _, phrase := range []string{testPhrase, testGermanPhrase, testWalrusPhrase} {
+		for _, lang := range siad.Languages() {
+			conv, err := ConvertPhrase(phrase, SeedTypeSia, lang)
+			if err != nil {
+				t.Fatal(err)
+			} else if conv.Format != SeedTypeSia || conv.Language != string(lang) {
+				t.Fatalf("expected %s %s, got %s %s", SeedTypeSia, lang, conv.Format, conv.Language)
+			} else if detected, err := siad.DetectLanguage(conv.Phrase); err != nil {
+				t.Fatal(err)
+			} else if detected != lang {
+				t.Fatalf("expected language %q, got %q", lang, detected)
+			}
+
+			var a, b [32]byte
+			if err := PhraseToSeed(phrase, &a); err != nil {
+				t.Fatal(err)
+			} else if err := PhraseToSeed(conv.Phrase, &b); err != nil {
+				t.Fatal(err)
+			} else if a != b {
+				t.Fatal("converted phrase derives a different seed")
+			}
+		}
+	}
+
+	if conv, err := ConvertPhrase(testPhrase, SeedTypeSia, siad.German); err != nil {
+		t.Fatal(err)
+	} else if conv.Phrase != testGermanPhrase {
+		t.Fatalf("expected %q, got %q", testGermanPhrase, conv.Phrase)
+	} else if conv.SourceFormat != SeedTypeSia {
+		t.Fatalf("expected source format %q, got %q", SeedTypeSia, conv.SourceFormat)
+	}
+
+	if _, err := ConvertPhrase(testPhrase, SeedTypeWalrus, ""); !errors.Is(err, ErrNotConvertible) {
+		t.Fatalf("expected ErrNotConvertible, got %v", err)
+	} else if conv, err := ConvertPhrase(testWalrusPhrase, SeedTypeWalrus, ""); err != nil {
+		t.Fatal(err)
+	} else if conv.Phrase != testWalrusPhrase {
+		t.Fatalf("expected %q, got %q", testWalrusPhrase, conv.Phrase)
+	}
+
+	if _, err := ConvertPhrase(testPhrase, SeedTypeSia, "klingon"); !errors.Is(err, siad.ErrUnknownLanguage) {
+		t.Fatalf("expected ErrUnknownLanguage, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.sia.tech/core/types"
+)
+
+type (
+	// A DecodedTransaction is a decoded v1 transaction with the IDs of the
+	// transaction and the outputs it creates.
+	DecodedTransaction struct {
+		ID                    types.TransactionID     `json:"id"`
+		Transaction           types.Transaction       `json:"transaction"`
+		SiacoinOutputIDs      []types.SiacoinOutputID `json:"siacoin_output_ids"`
+		SiafundOutputIDs      []types.SiafundOutputID `json:"siafund_output_ids"`
+		SiafundClaimOutputIDs []types.SiacoinOutputID `json:"siafund_claim_output_ids"`
+		FileContractIDs       []types.FileContractID  `json:"file_contract_ids"`
+	}
+
+	// A DecodedV2Transaction is a decoded v2 transaction with the IDs of the
+	// transaction and the outputs it creates.
+	DecodedV2Transaction struct {
+		ID                    types.TransactionID     `json:"id"`
+		Transaction           types.V2Transaction     `json:"transaction"`
+		SiacoinOutputIDs      []types.SiacoinOutputID `json:"siacoin_output_ids"`
+		SiafundOutputIDs      []types.SiafundOutputID `json:"siafund_output_ids"`
+		SiafundClaimOutputIDs []types.SiacoinOutputID `json:"siafund_claim_output_ids"`
+		FileContractIDs       []types.FileContractID  `json:"file_contract_ids"`
+	}
+)
+
+// EncodeTransaction returns the Sia encoding of a v1 transaction.
+func EncodeTransaction(txn types.Transaction) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := types.NewEncoder(buf)
+	txn.EncodeTo(enc)
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeV2Transaction returns the Sia encoding of a v2 transaction's
+// semantics. The semantic encoding omits signatures and parent elements, so it
+// cannot be decoded back into a transaction; use EncodeV2TransactionFull for
+// that.
+func EncodeV2Transaction(txn types.V2Transaction) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := types.NewEncoder(buf)
+	types.V2TransactionSemantics(txn).EncodeTo(enc)
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeV2TransactionFull returns the full Sia encoding of a v2 transaction,
+// including parent elements and signatures. It can be decoded with
+// DecodeV2Transaction.
+func EncodeV2TransactionFull(txn types.V2Transaction) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	enc := types.NewEncoder(buf)
+	txn.EncodeTo(enc)
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeExact decodes buf into v and returns an error if any bytes are left
+// over.
+func decodeExact(buf []byte, v interface {
+	types.DecoderFrom
+	types.EncoderTo
+}) error {
+	d := types.NewBufDecoder(buf)
+	v.DecodeFrom(d)
+	if err := d.Err(); err != nil {
+		return err
+	}
+
+	var reencoded bytes.Buffer
+	e := types.NewEncoder(&reencoded)
+	v.EncodeTo(e)
+	if err := e.Flush(); err != nil {
+		return err
+	} else if reencoded.Len() != len(buf) {
+		return fmt.Errorf("unexpected %d trailing bytes", len(buf)-reencoded.Len())
+	}
+	return nil
+}
+
+// DecodeTransaction decodes a Sia encoded v1 transaction.
+func DecodeTransaction(buf []byte) (DecodedTransaction, error) {
+	var txn types.Transaction
+	if err := decodeExact(buf, &txn); err != nil {
+		return DecodedTransaction{}, fmt.Errorf("error decoding transaction: %w", err)
+	}
+
+	decoded := DecodedTransaction{
+		ID:                    txn.ID(),
+		Transaction:           txn,
+		SiacoinOutputIDs:      make([]types.SiacoinOutputID, 0, len(txn.SiacoinOutputs)),
+		SiafundOutputIDs:      make([]types.SiafundOutputID, 0, len(txn.SiafundOutputs)),
+		SiafundClaimOutputIDs: make([]types.SiacoinOutputID, 0, len(txn.SiafundInputs)),
+		FileContractIDs:       make([]types.FileContractID, 0, len(txn.FileContracts)),
+	}
+	for i := range txn.SiacoinOutputs {
+		decoded.SiacoinOutputIDs = append(decoded.SiacoinOutputIDs, txn.SiacoinOutputID(i))
+	}
+	for i := range txn.SiafundOutputs {
+		decoded.SiafundOutputIDs = append(decoded.SiafundOutputIDs, txn.SiafundOutputID(i))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		decoded.SiafundClaimOutputIDs = append(decoded.SiafundClaimOutputIDs, sfi.ParentID.ClaimOutputID())
+	}
+	for i := range txn.FileContracts {
+		decoded.FileContractIDs = append(decoded.FileContractIDs, txn.FileContractID(i))
+	}
+	return decoded, nil
+}
+
+// DecodeV2Transaction decodes a Sia encoded v2 transaction. The buffer must
+// contain the full transaction encoding, including parent elements and
+// signatures as returned by EncodeV2TransactionFull, not the semantic encoding
+// returned by EncodeV2Transaction.
+func DecodeV2Transaction(buf []byte) (DecodedV2Transaction, error) {
+	var txn types.V2Transaction
+	if err := decodeExact(buf, &txn); err != nil {
+		// the semantic encoding is the most likely mistake, so name the
+		// expected encoding
+		return DecodedV2Transaction{}, fmt.Errorf("error decoding v2 transaction, expected the full encoding returned by EncodeV2TransactionFull: %w", err)
+	}
+
+	txnID := txn.ID()
+	decoded := DecodedV2Transaction{
+		ID:                    txnID,
+		Transaction:           txn,
+		SiacoinOutputIDs:      make([]types.SiacoinOutputID, 0, len(txn.SiacoinOutputs)),
+		SiafundOutputIDs:      make([]types.SiafundOutputID, 0, len(txn.SiafundOutputs)),
+		SiafundClaimOutputIDs: make([]types.SiacoinOutputID, 0, len(txn.SiafundInputs)),
+		FileContractIDs:       make([]types.FileContractID, 0, len(txn.FileContracts)),
+	}
+	for i := range txn.SiacoinOutputs {
+		decoded.SiacoinOutputIDs = append(decoded.SiacoinOutputIDs, txn.SiacoinOutputID(txnID, i))
+	}
+	for i := range txn.SiafundOutputs {
+		decoded.SiafundOutputIDs = append(decoded.SiafundOutputIDs, txn.SiafundOutputID(txnID, i))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		decoded.SiafundClaimOutputIDs = append(decoded.SiafundClaimOutputIDs, sfi.Parent.ID.V2ClaimOutputID())
+	}
+	for i := range txn.FileContracts {
+		decoded.FileContractIDs = append(decoded.FileContractIDs, txn.V2FileContractID(txnID, i))
+	}
+	return decoded, nil
+}
+
+// Encodings accepted by DecodeString.
+const (
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+)
+
+// DecodeString decodes a string in the given encoding. Hex may be prefixed
+// with 0x and base64 may use the standard or URL alphabet, with or without
+// padding. The encoding must be given because a base64 string may consist
+// only of hex characters.
+func DecodeString(s, encoding string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, errors.New("empty input")
+	}
+
+	switch encoding {
+	case EncodingHex:
+		buf, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("input is not valid hex: %w", err)
+		}
+		return buf, nil
+	case EncodingBase64:
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			if buf, err := enc.DecodeString(s); err == nil {
+				return buf, nil
+			}
+		}
+		return nil, errors.New("input is not valid base64")
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
@@ -0,0 +1,128 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: frand.Entropy256()}},
+		SiacoinOutputs: []types.SiacoinOutput{
+			{Address: frand.Entropy256(), Value: types.Siacoins(1)},
+			{Address: frand.Entropy256(), Value: types.Siacoins(2)},
+		},
+		SiafundInputs:  []types.SiafundInput{{ParentID: frand.Entropy256()}},
+		SiafundOutputs: []types.SiafundOutput{{Address: frand.Entropy256(), Value: 10}},
+		MinerFees:      []types.Currency{types.Siacoins(1)},
+		Signatures:     []types.TransactionSignature{{ParentID: frand.Entropy256(), Signature: frand.Bytes(64)}},
+	}
+
+	buf, err := EncodeTransaction(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeTransaction(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if decoded.ID != txn.ID() {
+		t.Fatalf("expected ID %v, got %v", txn.ID(), decoded.ID)
+	} else if len(decoded.SiacoinOutputIDs) != 2 || decoded.SiacoinOutputIDs[1] != txn.SiacoinOutputID(1) {
+		t.Fatal("unexpected siacoin output IDs")
+	} else if len(decoded.SiafundOutputIDs) != 1 || decoded.SiafundOutputIDs[0] != txn.SiafundOutputID(0) {
+		t.Fatal("unexpected siafund output IDs")
+	} else if len(decoded.SiafundClaimOutputIDs) != 1 || decoded.SiafundClaimOutputIDs[0] != txn.SiafundInputs[0].ParentID.ClaimOutputID() {
+		t.Fatal("unexpected siafund claim output IDs")
+	}
+
+	reencoded, err := EncodeTransaction(decoded.Transaction)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, reencoded) {
+		t.Fatal("roundtrip mismatch")
+	}
+
+	if _, err := DecodeTransaction(append(buf, 0)); err == nil {
+		t.Fatal("expected trailing bytes error")
+	} else if _, err := DecodeTransaction(buf[:len(buf)-1]); err == nil {
+		t.Fatal("expected truncated transaction error")
+	}
+}
+
+func TestDecodeV2Transaction(t *testing.T) {
+	txn := types.V2Transaction{
+		SiacoinInputs: []types.V2SiacoinInput{{
+			Parent: types.SiacoinElement{
+				ID:            frand.Entropy256(),
+				SiacoinOutput: types.SiacoinOutput{Address: frand.Entropy256(), Value: types.Siacoins(3)},
+			},
+			SatisfiedPolicy: types.SatisfiedPolicy{
+				Policy:     types.PolicyPublicKey(types.GeneratePrivateKey().PublicKey()),
+				Signatures: []types.Signature{types.Signature(frand.Bytes(64))},
+			},
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{Address: frand.Entropy256(), Value: types.Siacoins(2)}},
+		MinerFee:       types.Siacoins(1),
+	}
+
+	buf, err := EncodeV2TransactionFull(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode from each supported string encoding
+	encoded := map[string]string{
+		EncodingHex:    hex.EncodeToString(buf),
+		EncodingBase64: base64.StdEncoding.EncodeToString(buf),
+	}
+	for encoding, s := range encoded {
+		b, err := DecodeString(s, encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := DecodeV2Transaction(b)
+		if err != nil {
+			t.Fatal(err)
+		} else if decoded.ID != txn.ID() {
+			t.Fatalf("expected ID %v, got %v", txn.ID(), decoded.ID)
+		} else if len(decoded.SiacoinOutputIDs) != 1 || decoded.SiacoinOutputIDs[0] != txn.SiacoinOutputID(txn.ID(), 0) {
+			t.Fatal("unexpected siacoin output IDs")
+		} else if decoded.Transaction.SiacoinInputs[0].Parent.ID != txn.SiacoinInputs[0].Parent.ID {
+			t.Fatal("parent mismatch")
+		}
+
+		reencoded, err := EncodeV2TransactionFull(decoded.Transaction)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf, reencoded) {
+			t.Fatal("roundtrip mismatch")
+		}
+	}
+
+	// the semantic encoding omits the parents and signatures
+	if semantics, err := EncodeV2Transaction(txn); err != nil {
+		t.Fatal(err)
+	} else if _, err := DecodeV2Transaction(semantics); err == nil {
+		t.Fatal("expected the semantic encoding to fail to decode")
+	}
+
+	if _, err := DecodeString("not hex or base64!", EncodingBase64); err == nil {
+		t.Fatal("expected decode error")
+	} else if _, err := DecodeString("abcd", "base32"); err == nil {
+		t.Fatal("expected unknown encoding error")
+	}
+
+	// base64 made only of hex characters is not mistaken for hex
+	if b, err := DecodeString("abcd", EncodingBase64); err != nil {
+		t.Fatal(err)
+	} else if want, _ := base64.StdEncoding.DecodeString("abcd"); !bytes.Equal(b, want) {
+		t.Fatalf("expected %x, got %x", want, b)
+	}
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"fmt"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+// A TransactionFormat is the version of a transaction.
+type TransactionFormat string
+
+// Transaction formats.
+const (
+	FormatV1 TransactionFormat = "v1"
+	FormatV2 TransactionFormat = "v2"
+)
+
+// A SignedTransaction is a v1 or v2 transaction signed by
+// BuildSignedTransaction. Only the field matching Format is set.
+type SignedTransaction struct {
+	Format        TransactionFormat    `json:"format"`
+	Basis         types.ChainIndex     `json:"basis"`
+	Transaction   *types.Transaction   `json:"transaction,omitempty"`
+	V2Transaction *types.V2Transaction `json:"v2_transaction,omitempty"`
+	Fee           types.Currency       `json:"fee"`
+	Change        types.Currency       `json:"change"`
+}
+
+// TransactionFormatFor returns the format of transactions to build for the
+// block after cs. v1 transactions are built until the v2 allow height and v2
+// transactions after it. Between the allow and require heights both formats
+// are valid and v2 is chosen; set BuildRequest.Format to build v1 there
+// instead.
+func TransactionFormatFor(cs consensus.State) TransactionFormat {
+	if cs.Index.Height+1 < cs.Network.HardforkV2.AllowHeight {
+		return FormatV1
+	}
+	return FormatV2
+}
+
+// CheckTransactionFormat returns an error if transactions of the format are
+// not valid in the block after cs.
+func CheckTransactionFormat(cs consensus.State, format TransactionFormat) error {
+	childHeight := cs.Index.Height + 1
+	switch format {
+	case FormatV1:
+		if childHeight >= cs.Network.HardforkV2.RequireHeight {
+			return fmt.Errorf("v1 transactions are not allowed after height %d", cs.Network.HardforkV2.RequireHeight)
+		}
+	case FormatV2:
+		if childHeight < cs.Network.HardforkV2.AllowHeight {
+			return fmt.Errorf("v2 transactions are not allowed until height %d", cs.Network.HardforkV2.AllowHeight)
+		}
+	default:
+		return fmt.Errorf("unknown transaction format %q", format)
+	}
+	return nil
+}
+
+// BuildSignedTransaction builds and signs a transaction in the format valid
+// for the block after cs. req.Format selects the format if it is set.
+func BuildSignedTransaction(cs consensus.State, seed *[32]byte, utxos []types.SiacoinElement, req BuildRequest) (SignedTransaction, error) {
+	format := req.Format
+	if format == "" {
+		format = TransactionFormatFor(cs)
+	} else if err := CheckTransactionFormat(cs, format); err != nil {
+		return SignedTransaction{}, err
+	}
+
+	switch format {
+	case FormatV1:
+		resp, err := BuildTransaction(cs, utxos, req)
+		if err != nil {
+			return SignedTransaction{}, err
+		} else if err := SignTransaction(cs, seed, &resp.Transaction, resp.SigningIndices); err != nil {
+			return SignedTransaction{}, fmt.Errorf("failed to sign transaction: %w", err)
+		}
+		return SignedTransaction{
+			Format:      format,
+			Basis:       resp.Basis,
+			Transaction: &resp.Transaction,
+			Fee:         resp.Fee,
+			Change:      resp.Change,
+		}, nil
+	default:
+		resp, err := BuildV2Transaction(cs, utxos, req)
+		if err != nil {
+			return SignedTransaction{}, err
+		} else if err := SignV2Transaction(cs, seed, &resp.Transaction, resp.SigningIndices); err != nil {
+			return SignedTransaction{}, fmt.Errorf("failed to sign transaction: %w", err)
+		}
+		return SignedTransaction{
+			Format:        format,
+			Basis:         resp.Basis,
+			V2Transaction: &resp.Transaction,
+			Fee:           resp.Fee,
+			Change:        resp.Change,
+		}, nil
+	}
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestTransactionFormat(t *testing.T) {
+	cs := testState(0)
+	allow, require := cs.Network.HardforkV2.AllowHeight, cs.Network.HardforkV2.RequireHeight
+
+	tests := []struct {
+		height uint64
+		format TransactionFormat
+		v1, v2 bool
+	}{
+		{allow - 2, FormatV1, true, false},
+		{allow - 1, FormatV2, true, true},
+		{require - 2, FormatV2, true, true},
+		{require - 1, FormatV2, false, true},
+	}
+	for _, test := range tests {
+		cs := testState(test.height)
+		if format := TransactionFormatFor(cs); format != test.format {
+			t.Fatalf("height %d: expected %q, got %q", test.height, test.format, format)
+		} else if err := CheckTransactionFormat(cs, FormatV1); (err == nil) != test.v1 {
+			t.Fatalf("height %d: unexpected v1 result %v", test.height, err)
+		} else if err := CheckTransactionFormat(cs, FormatV2); (err == nil) != test.v2 {
+			t.Fatalf("height %d: unexpected v2 result %v", test.height, err)
+		}
+	}
+
+	if err := CheckTransactionFormat(cs, "v3"); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
+
+func TestBuildSignedTransaction(t *testing.T) {
+	seed := testSeed(t)
+	addresses := GenerateAddresses(seed, 0, 2)
+	feeRate := types.Siacoins(1).Div64(1000)
+	utxos := testElements(addresses, types.Siacoins(10), types.Siacoins(20))
+	req := BuildRequest{
+		Addresses:  addresses,
+		Recipients: []Recipient{{Address: frand.Entropy256(), Value: types.Siacoins(25)}},
+		FeeRate:    feeRate,
+	}
+
+	// before the allow height a v1 transaction is built
+	cs := testState(500000)
+	signed, err := BuildSignedTransaction(cs, seed, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if signed.Format != FormatV1 || signed.Transaction == nil || signed.V2Transaction != nil {
+		t.Fatalf("expected a v1 transaction, got %q", signed.Format)
+	}
+	txn := *signed.Transaction
+	var in, out types.Currency
+	for _, sce := range utxos {
+		in = in.Add(sce.SiacoinOutput.Value)
+	}
+	for _, sco := range txn.SiacoinOutputs {
+		out = out.Add(sco.Value)
+	}
+	if len(txn.MinerFees) != 1 || !in.Equals(out.Add(txn.MinerFees[0])) {
+		t.Fatal("unbalanced transaction")
+	} else if minFee := feeRate.Mul64(cs.TransactionWeight(txn)); txn.MinerFees[0].Cmp(minFee) < 0 {
+		t.Fatalf("expected fee of at least %v, got %v", minFee, txn.MinerFees[0])
+	}
+	for i, sig := range txn.Signatures {
+		var pk types.PublicKey
+		copy(pk[:], txn.SiacoinInputs[i].UnlockConditions.PublicKeys[0].Key)
+		if types.Hash256(txn.SiacoinInputs[i].ParentID) != sig.ParentID {
+			t.Fatalf("signature %d does not match input", i)
+		} else if !pk.VerifyHash(cs.WholeSigHash(txn, sig.ParentID, 0, 0, nil), types.Signature(sig.Signature)) {
+			t.Fatalf("invalid signature %d", i)
+		}
+	}
+
+	// after the require height a v2 transaction is built and v1 signing is
+	// refused
+	cs = testState(600000)
+	signed, err = BuildSignedTransaction(cs, seed, utxos, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if signed.Format != FormatV2 || signed.V2Transaction == nil || signed.Transaction != nil {
+		t.Fatalf("expected a v2 transaction, got %q", signed.Format)
+	} else if len(signed.V2Transaction.SiacoinInputs[0].SatisfiedPolicy.Signatures) != 1 {
+		t.Fatal("expected the transaction to be signed")
+	}
+	if err := SignTransaction(cs, seed, &txn, []uint64{0, 1}); err == nil {
+		t.Fatal("expected v1 signing to be refused")
+	} else if _, err := BuildTransaction(cs, utxos, req); err == nil {
+		t.Fatal("expected v1 building to be refused")
+	}
+
+	// v1 can still be requested during the transition, but not after it
+	req.Format = FormatV1
+	if _, err := BuildSignedTransaction(cs, seed, utxos, req); err == nil {
+		t.Fatal("expected v1 to be refused after the require height")
+	}
+	cs = testState(cs.Network.HardforkV2.AllowHeight)
+	if signed, err := BuildSignedTransaction(cs, seed, utxos, req); err != nil {
+		t.Fatal(err)
+	} else if signed.Format != FormatV1 || signed.Transaction == nil {
+		t.Fatalf("expected a v1 transaction, got %q", signed.Format)
+	}
+}
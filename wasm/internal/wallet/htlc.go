@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+// An HTLC is a hash time locked v2 address. It can be claimed by the claim
+// key with the preimage of Hash, or refunded to the refund key once the chain
+// reaches the timeout height.
+type HTLC struct {
+	ClaimKey  types.PublicKey `json:"claim_key"`
+	RefundKey types.PublicKey `json:"refund_key"`
+	// Hash is the SHA-256 hash of the preimage.
+	Hash    types.Hash256     `json:"hash"`
+	Timeout uint64            `json:"timeout"`
+	Address types.Address     `json:"address"`
+	Policy  types.SpendPolicy `json:"policy"`
+}
+
+// htlcPolicy returns the spend policy of an HTLC.
+func htlcPolicy(claimKey, refundKey types.PublicKey, hash types.Hash256, timeout uint64) types.SpendPolicy {
+	return types.PolicyThreshold(1, []types.SpendPolicy{
+		types.PolicyThreshold(2, []types.SpendPolicy{types.PolicyPublicKey(claimKey), types.PolicyHash(hash)}),
+		types.PolicyThreshold(2, []types.SpendPolicy{types.PolicyPublicKey(refundKey), types.PolicyAbove(timeout)}),
+	})
+}
+
+// NewHTLC returns the HTLC claimable by claimKey with the preimage of hash or
+// refundable to refundKey at the timeout height.
+func NewHTLC(claimKey, refundKey types.PublicKey, hash types.Hash256, timeout uint64) (HTLC, error) {
+	if claimKey == refundKey {
+		return HTLC{}, errors.New("claim and refund keys must differ")
+	} else if timeout == 0 {
+		return HTLC{}, errors.New("timeout height must be set")
+	}
+
+	policy := htlcPolicy(claimKey, refundKey, hash, timeout)
+	return HTLC{
+		ClaimKey:  claimKey,
+		RefundKey: refundKey,
+		Hash:      hash,
+		Timeout:   timeout,
+		Address:   policy.Address(),
+		Policy:    policy,
+	}, nil
+}
+
+// NewPreimage returns a random preimage and its hash.
+func NewPreimage() ([32]byte, types.Hash256) {
+	preimage := frand.Entropy256()
+	return preimage, sha256.Sum256(preimage[:])
+}
+
+// htlcInputs returns the outputs in utxos sent to the HTLC, signed by the key
+// at index, which must be the HTLC's claim or refund key.
+func htlcInputs(cs consensus.State, seed *[32]byte, index uint64, htlc HTLC, utxos []types.SiacoinElement, key types.PublicKey) ([]sweepInput, error) {
+	sk := wallet.KeyFromSeed(seed, index)
+	if sk.PublicKey() != key {
+		return nil, fmt.Errorf("key %d does not match the HTLC", index)
+	}
+
+	policy := htlcPolicy(htlc.ClaimKey, htlc.RefundKey, htlc.Hash, htlc.Timeout)
+	if policy.Address() != htlc.Address {
+		return nil, errors.New("HTLC address does not match its policy")
+	}
+
+	var inputs []sweepInput
+	for _, sce := range utxos {
+		if sce.SiacoinOutput.Address != htlc.Address || sce.MaturityHeight > cs.Index.Height {
+			continue
+		}
+		inputs = append(inputs, sweepInput{Parent: sce, Policy: policy, Key: sk})
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("no HTLC outputs to spend")
+	}
+	return inputs, nil
+}
+
+// ClaimHTLC returns a signed transaction sending the HTLC's outputs in utxos
+// to the destination with the preimage. The key at index must be the claim
+// key. Claiming reveals the preimage on chain.
+func ClaimHTLC(cs consensus.State, seed *[32]byte, index uint64, htlc HTLC, utxos []types.SiacoinElement, preimage [32]byte, destination types.Address, feeRate types.Currency) (types.V2Transaction, error) {
+	if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return types.V2Transaction{}, err
+	} else if sha256.Sum256(preimage[:]) != htlc.Hash {
+		return types.V2Transaction{}, errors.New("preimage does not match the HTLC hash")
+	}
+
+	inputs, err := htlcInputs(cs, seed, index, htlc, utxos, htlc.ClaimKey)
+	if err != nil {
+		return types.V2Transaction{}, err
+	}
+	return sweepOutputs(cs, inputs, map[types.Hash256][32]byte{htlc.Hash: preimage}, destination, feeRate)
+}
+
+// RefundHTLC returns a signed transaction sending the HTLC's outputs in
+// utxos to the destination. The key at index must be the refund key and the
+// timeout height must have been reached.
+func RefundHTLC(cs consensus.State, seed *[32]byte, index uint64, htlc HTLC, utxos []types.SiacoinElement, destination types.Address, feeRate types.Currency) (types.V2Transaction, error) {
+	if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return types.V2Transaction{}, err
+	} else if cs.Index.Height < htlc.Timeout {
+		return types.V2Transaction{}, fmt.Errorf("HTLC cannot be refunded until height %d", htlc.Timeout)
+	}
+
+	inputs, err := htlcInputs(cs, seed, index, htlc, utxos, htlc.RefundKey)
+	if err != nil {
+		return types.V2Transaction{}, err
+	}
+	return sweepOutputs(cs, inputs, nil, destination, feeRate)
+}
+
+// ExtractHTLCPreimage returns the preimage revealed by a confirmed claim of
+// the HTLC in events.
+func ExtractHTLCPreimage(events []wallet.Event, htlc HTLC) ([32]byte, bool) {
+	for _, event := range events {
+		txn, ok := event.Data.(wallet.EventV2Transaction)
+		if !ok {
+			continue
+		}
+		for _, sci := range txn.SiacoinInputs {
+			if sci.Parent.SiacoinOutput.Address != htlc.Address {
+				continue
+			}
+			for _, preimage := range sci.SatisfiedPolicy.Preimages {
+				if sha256.Sum256(preimage[:]) == htlc.Hash {
+					return preimage, true
+				}
+			}
+		}
+	}
+	return [32]byte{}, false
+}
+
+// FindHTLCPreimage searches the HTLC address's confirmed events for a claim
+// that revealed the preimage.
+func FindHTLCPreimage(w backend.Backend, htlc HTLC) ([32]byte, bool, error) {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		events, err := w.BatchAddressEvents([]types.Address{htlc.Address}, offset, pageSize)
+		if err != nil {
+			return [32]byte{}, false, fmt.Errorf("failed to get HTLC events: %w", err)
+		} else if preimage, ok := ExtractHTLCPreimage(events, htlc); ok {
+			return preimage, true, nil
+		} else if len(events) < pageSize {
+			return [32]byte{}, false, nil
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+func TestHTLC(t *testing.T) {
+	claimSeed, refundSeed := testSeed(t), testSeed(t)
+	claimKey := wallet.KeyFromSeed(claimSeed, 1).PublicKey()
+	refundKey := wallet.KeyFromSeed(refundSeed, 2).PublicKey()
+	preimage, hash := NewPreimage()
+
+	base := testState(600000)
+	htlc, err := NewHTLC(claimKey, refundKey, hash, base.Index.Height+2)
+	if err != nil {
+		t.Fatal(err)
+	} else if htlc.Address != htlcPolicy(claimKey, refundKey, hash, htlc.Timeout).Address() {
+		t.Fatal("address does not match policy")
+	}
+
+	cs, sces := testChainElements(base, types.SiacoinOutput{Address: htlc.Address, Value: types.Siacoins(10)})
+	destination := types.Address(frand.Entropy256())
+	feeRate := types.Siacoins(1).Div64(1000)
+
+	// the claim requires the claim key and the correct preimage
+	if _, err := ClaimHTLC(cs, claimSeed, 1, htlc, sces, [32]byte{1}, destination, feeRate); err == nil {
+		t.Fatal("expected preimage error")
+	} else if _, err := ClaimHTLC(cs, refundSeed, 2, htlc, sces, preimage, destination, feeRate); err == nil {
+		t.Fatal("expected key error")
+	}
+
+	claim, err := ClaimHTLC(cs, claimSeed, 1, htlc, sces, preimage, destination, feeRate)
+	if err != nil {
+		t.Fatal(err)
+	} else if res := ValidateV2Transaction(cs, claim, sces, nil, feeRate); !res.Valid {
+		t.Fatalf("expected valid claim, got %v", res.Issues)
+	}
+
+	// the refund is not valid until the timeout
+	if _, err := RefundHTLC(cs, refundSeed, 2, htlc, sces, destination, feeRate); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	for cs.Index.Height < htlc.Timeout {
+		b := types.Block{
+			ParentID:  cs.Index.ID,
+			Timestamp: time.Now(),
+			V2:        &types.V2BlockData{Height: cs.Index.Height + 1},
+		}
+		var au consensus.ApplyUpdate
+		cs, au = consensus.ApplyBlock(cs, b, consensus.V1BlockSupplement{}, time.Time{})
+		au.UpdateElementProof(&sces[0].StateElement)
+	}
+	if _, err := RefundHTLC(cs, claimSeed, 1, htlc, sces, destination, feeRate); err == nil {
+		t.Fatal("expected key error")
+	}
+	refund, err := RefundHTLC(cs, refundSeed, 2, htlc, sces, destination, feeRate)
+	if err != nil {
+		t.Fatal(err)
+	} else if res := ValidateV2Transaction(cs, refund, sces, nil, feeRate); !res.Valid {
+		t.Fatalf("expected valid refund, got %v", res.Issues)
+	}
+
+	// the counterparty learns the preimage from the confirmed claim
+	b := backend.NewMemory(cs)
+	b.AddEvents(wallet.Event{
+		ID:       types.Hash256(claim.ID()),
+		Index:    cs.Index,
+		Type:     wallet.EventTypeV2Transaction,
+		Data:     wallet.EventV2Transaction(claim),
+		Relevant: []types.Address{htlc.Address},
+	})
+	if _, ok := ExtractHTLCPreimage(nil, htlc); ok {
+		t.Fatal("expected no preimage")
+	} else if revealed, ok, err := FindHTLCPreimage(b, htlc); err != nil {
+		t.Fatal(err)
+	} else if !ok || revealed != preimage {
+		t.Fatal("expected revealed preimage")
+	}
+}
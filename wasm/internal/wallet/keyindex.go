@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+// maxKeyScan is the maximum number of keys derived by a KeySource scan.
+const maxKeyScan = 100000
+
+type (
+	// A KeyIndex maps wallet addresses to the index of the key that
+	// derives them.
+	KeyIndex map[types.Address]uint64
+
+	// A KeySource describes where to find the signing key of each input.
+	// Either Addresses is set to the wallet's known addresses or ScanCount
+	// keys starting at ScanStart are derived.
+	KeySource struct {
+		Addresses map[types.Address]uint64 `json:"addresses,omitempty"`
+		ScanStart uint64                   `json:"scan_start,omitempty"`
+		ScanCount int                      `json:"scan_count,omitempty"`
+	}
+
+	// An UnsignedInput is an input that could not be signed.
+	UnsignedInput struct {
+		// Type is "siacoin" or "siafund".
+		Type     string        `json:"type"`
+		Index    int           `json:"index"`
+		ParentID types.Hash256 `json:"parent_id"`
+		Address  types.Address `json:"address"`
+		Reason   string        `json:"reason"`
+	}
+)
+
+// NewKeyIndex returns the key index of the wallet's addresses.
+func NewKeyIndex(addresses []Address) KeyIndex {
+	ki := make(KeyIndex, len(addresses))
+	for _, addr := range addresses {
+		ki[addr.Address] = addr.Index
+	}
+	return ki
+}
+
+// ScanKeyIndex returns the key index of the v1 and v2 addresses of n keys
+// starting at index start.
+func ScanKeyIndex(seed *[32]byte, start uint64, n int) KeyIndex {
+	ki := make(KeyIndex, 2*n)
+	for i := start; i < start+uint64(n); i++ {
+		addr := GenerateAddress(seed, i)
+		ki[addr.Address] = i
+		ki[types.PolicyPublicKey(addr.PublicKey).Address()] = i
+	}
+	return ki
+}
+
+// KeyIndex returns the key index described by the source.
+func (ks KeySource) KeyIndex(seed *[32]byte) (KeyIndex, error) {
+	switch {
+	case len(ks.Addresses) != 0 && ks.ScanCount != 0:
+		return nil, errors.New("only one of addresses or scan count can be set")
+	case len(ks.Addresses) != 0:
+		return KeyIndex(ks.Addresses), nil
+	case ks.ScanCount == 0 && ks.ScanStart == 0:
+		return nil, errors.New("no addresses or scan count")
+	case ks.ScanCount <= 0 || ks.ScanCount > maxKeyScan:
+		return nil, fmt.Errorf("scan count must be between 1 and %d", maxKeyScan)
+	default:
+		return ScanKeyIndex(seed, ks.ScanStart, ks.ScanCount), nil
+	}
+}
+
+// SignTransactionWithKeys signs each input of a v1 transaction whose parent
+// address is in the key index and returns the inputs that could not be
+// signed. Each input is matched to its signatures by parent ID, so the order
+// of the inputs and signatures does not matter, and every signature of the
+// input is signed.
+func SignTransactionWithKeys(cs consensus.State, seed *[32]byte, txn *types.Transaction, keys KeyIndex) ([]UnsignedInput, error) {
+	sigIndices := make(map[types.Hash256][]int, len(txn.Signatures))
+	for i, sig := range txn.Signatures {
+		sigIndices[sig.ParentID] = append(sigIndices[sig.ParentID], i)
+	}
+
+	var unsigned []UnsignedInput
+	indices := make(map[int]uint64)
+	match := func(typ string, i int, parentID types.Hash256, addr types.Address) {
+		index, ok := keys[addr]
+		if !ok {
+			unsigned = append(unsigned, UnsignedInput{Type: typ, Index: i, ParentID: parentID, Address: addr, Reason: "address is not in the wallet"})
+			return
+		}
+		sigs, ok := sigIndices[parentID]
+		if !ok {
+			unsigned = append(unsigned, UnsignedInput{Type: typ, Index: i, ParentID: parentID, Address: addr, Reason: "input has no signature"})
+			return
+		}
+		for _, sig := range sigs {
+			indices[sig] = index
+		}
+	}
+	for i, sci := range txn.SiacoinInputs {
+		match("siacoin", i, types.Hash256(sci.ParentID), sci.UnlockConditions.UnlockHash())
+	}
+	for i, sfi := range txn.SiafundInputs {
+		match("siafund", i, types.Hash256(sfi.ParentID), sfi.UnlockConditions.UnlockHash())
+	}
+
+	if err := signV1(cs, seed, txn, indices); err != nil {
+		return nil, err
+	}
+	return unsigned, nil
+}
+
+// SignV2TransactionWithKeys signs each input of a v2 transaction whose parent
+// address is in the key index and returns the inputs that could not be
+// signed.
+func SignV2TransactionWithKeys(cs consensus.State, seed *[32]byte, txn *types.V2Transaction, keys KeyIndex) ([]UnsignedInput, error) {
+	var unsigned []UnsignedInput
+	scIndices := make(map[int]uint64)
+	for i, sci := range txn.SiacoinInputs {
+		if index, ok := keys[sci.Parent.SiacoinOutput.Address]; ok {
+			scIndices[i] = index
+		} else {
+			unsigned = append(unsigned, UnsignedInput{Type: "siacoin", Index: i, ParentID: types.Hash256(sci.Parent.ID), Address: sci.Parent.SiacoinOutput.Address, Reason: "address is not in the wallet"})
+		}
+	}
+	sfIndices := make(map[int]uint64)
+	for i, sfi := range txn.SiafundInputs {
+		if index, ok := keys[sfi.Parent.SiafundOutput.Address]; ok {
+			sfIndices[i] = index
+		} else {
+			unsigned = append(unsigned, UnsignedInput{Type: "siafund", Index: i, ParentID: types.Hash256(sfi.Parent.ID), Address: sfi.Parent.SiafundOutput.Address, Reason: "address is not in the wallet"})
+		}
+	}
+
+	if err := signV2(cs, seed, txn, scIndices, sfIndices); err != nil {
+		return nil, err
+	}
+	return unsigned, nil
+}
@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+func TestKeySource(t *testing.T) {
+	seed := testSeed(t)
+
+	if _, err := (KeySource{}).KeyIndex(seed); err == nil {
+		t.Fatal("expected empty source error")
+	} else if _, err := (KeySource{ScanCount: maxKeyScan + 1}).KeyIndex(seed); err == nil {
+		t.Fatal("expected scan count error")
+	} else if _, err := (KeySource{ScanStart: 5}).KeyIndex(seed); err == nil {
+		t.Fatal("expected scan count error")
+	} else if _, err := (KeySource{ScanCount: -1}).KeyIndex(seed); err == nil {
+		t.Fatal("expected scan count error")
+	} else if _, err := (KeySource{Addresses: map[types.Address]uint64{{}: 0}, ScanCount: 1}).KeyIndex(seed); err == nil {
+		t.Fatal("expected conflicting source error")
+	}
+
+	ki, err := KeySource{ScanStart: 5, ScanCount: 2}.KeyIndex(seed)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ki) != 4 {
+		t.Fatalf("expected both address types for 2 keys, got %d addresses", len(ki))
+	} else if ki[GenerateAddress(seed, 6).Address] != 6 || ki[GeneratePolicyAddress(seed, 5).Address] != 5 {
+		t.Fatal("unexpected key index")
+	}
+}
+
+func TestSignV2TransactionWithKeys(t *testing.T) {
+	seed := testSeed(t)
+	owned := []Address{GenerateAddress(seed, 7), GeneratePolicyAddress(seed, 2)}
+	foreign := types.Address(frand.Entropy256())
+
+	cs, sces := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: owned[0].Address, Value: types.Siacoins(1)},
+		types.SiacoinOutput{Address: foreign, Value: types.Siacoins(2)},
+		types.SiacoinOutput{Address: owned[1].Address, Value: types.Siacoins(3)},
+	)
+	var txn types.V2Transaction
+	for _, sce := range sces {
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{Parent: sce})
+	}
+
+	unsigned, err := SignV2TransactionWithKeys(cs, seed, &txn, NewKeyIndex(owned))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(unsigned) != 1 || unsigned[0].Type != "siacoin" || unsigned[0].Address != foreign {
+		t.Fatalf("expected the foreign input to be reported, got %v", unsigned)
+	}
+	sigHash := cs.InputSigHash(txn)
+	for i, sci := range txn.SiacoinInputs {
+		if sci.Parent.SiacoinOutput.Address == foreign {
+			if len(sci.SatisfiedPolicy.Signatures) != 0 {
+				t.Fatalf("expected input %d to be unsigned", i)
+			}
+			continue
+		}
+		pk := policyKeys(sci.SatisfiedPolicy.Policy)[0]
+		if !pk.VerifyHash(sigHash, sci.SatisfiedPolicy.Signatures[0]) {
+			t.Fatalf("invalid signature for input %d", i)
+		}
+	}
+
+	// an address mapped to the wrong key is refused
+	wrong := KeyIndex{owned[0].Address: 8}
+	if _, err := SignV2TransactionWithKeys(cs, seed, &txn, wrong); err == nil {
+		t.Fatal("expected key mismatch error")
+	}
+}
+
+func TestSignTransactionWithKeys(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(500000)
+
+	// the signatures are in a different order than the inputs
+	var txn types.Transaction
+	for _, index := range []uint64{3, 1} {
+		pk := wallet.KeyFromSeed(seed, index).PublicKey()
+		id := types.SiacoinOutputID(frand.Entropy256())
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         id,
+			UnlockConditions: types.StandardUnlockConditions(pk),
+		})
+		txn.Signatures = append([]types.TransactionSignature{{
+			ParentID:      types.Hash256(id),
+			CoveredFields: types.CoveredFields{WholeTransaction: true},
+		}}, txn.Signatures...)
+	}
+	txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+		ParentID:         frand.Entropy256(),
+		UnlockConditions: types.StandardUnlockConditions(types.PublicKey{1}),
+	})
+
+	unsigned, err := SignTransactionWithKeys(cs, seed, &txn, ScanKeyIndex(seed, 0, 5))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(unsigned) != 1 || unsigned[0].Index != 2 {
+		t.Fatalf("expected input 2 to be reported, got %v", unsigned)
+	}
+	for i, sig := range txn.Signatures {
+		var pk types.PublicKey
+		for _, sci := range txn.SiacoinInputs {
+			if types.Hash256(sci.ParentID) == sig.ParentID {
+				pk, _ = unlockKeyToPublicKey(sci.UnlockConditions.PublicKeys[0])
+			}
+		}
+		if !pk.VerifyHash(cs.WholeSigHash(txn, sig.ParentID, 0, 0, nil), types.Signature(sig.Signature)) {
+			t.Fatalf("invalid signature %d", i)
+		}
+	}
+
+	// explicit indices in the wrong order are refused
+	if err := SignTransaction(cs, seed, &txn, []uint64{3, 1}); err == nil {
+		t.Fatal("expected key mismatch error")
+	}
+
+	// every signature of an input is signed
+	pk := wallet.KeyFromSeed(seed, 4).PublicKey()
+	id := types.SiacoinOutputID(frand.Entropy256())
+	txn = types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: id, UnlockConditions: types.StandardUnlockConditions(pk)}},
+		Signatures: []types.TransactionSignature{
+			{ParentID: types.Hash256(id), CoveredFields: types.CoveredFields{WholeTransaction: true}},
+			{ParentID: types.Hash256(id), Timelock: 10, CoveredFields: types.CoveredFields{WholeTransaction: true}},
+		},
+	}
+	if _, err := SignTransactionWithKeys(cs, seed, &txn, ScanKeyIndex(seed, 0, 5)); err != nil {
+		t.Fatal(err)
+	}
+	for i, sig := range txn.Signatures {
+		if !pk.VerifyHash(cs.WholeSigHash(txn, sig.ParentID, 0, sig.Timelock, nil), types.Signature(sig.Signature)) {
+			t.Fatalf("invalid signature %d", i)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// messageSignaturePrefix identifies the text encoding of a MessageSignature.
+const messageSignaturePrefix = "sia-msg-v1:"
+
+// A MessageSignature proves that the holder of a public key signed a
+// message. Its text form is "sia-msg-v1:ed25519:<public key>:<signature>".
+type MessageSignature struct {
+	PublicKey types.PublicKey
+	Signature types.Signature
+}
+
+// String implements fmt.Stringer.
+func (ms MessageSignature) String() string {
+	return messageSignaturePrefix + ms.PublicKey.String() + ":" + ms.Signature.String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ms MessageSignature) MarshalText() ([]byte, error) {
+	return []byte(ms.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (ms *MessageSignature) UnmarshalText(b []byte) error {
+	s, ok := strings.CutPrefix(strings.TrimSpace(string(b)), messageSignaturePrefix)
+	if !ok {
+		return errors.New("missing message signature prefix")
+	}
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return errors.New("missing signature")
+	} else if err := ms.PublicKey.UnmarshalText([]byte(s[:i])); err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	} else if err := ms.Signature.UnmarshalText([]byte(s[i+1:])); err != nil {
+		return fmt.Errorf("failed to parse signature: %w", err)
+	}
+	return nil
+}
+
+// MessageHash returns the hash signed by SignMessage. The distinguisher
+// prevents a message signature from being valid for a transaction.
+func MessageHash(message []byte) types.Hash256 {
+	h := types.NewHasher()
+	h.WriteDistinguisher("message")
+	h.E.WriteBytes(message)
+	return h.Sum()
+}
+
+// SignMessage signs the message with the key at index.
+func SignMessage(seed *[32]byte, index uint64, message []byte) MessageSignature {
+	sk := wallet.KeyFromSeed(seed, index)
+	defer clear(sk)
+	return MessageSignature{
+		PublicKey: sk.PublicKey(),
+		Signature: sk.SignHash(MessageHash(message)),
+	}
+}
+
+// VerifyMessage checks that the message was signed by the key of addr, which
+// may be the key's v1 or v2 address.
+func VerifyMessage(addr types.Address, message []byte, ms MessageSignature) error {
+	if !keyOwnsAddress(ms.PublicKey, addr) {
+		return fmt.Errorf("public key %v does not belong to address %v", ms.PublicKey, addr)
+	} else if !ms.PublicKey.VerifyHash(MessageHash(message), ms.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// VerifyMessageUnlockConditions checks that the message was signed by a key
+// that can spend from the unlock conditions alone and returns their address.
+// Unlock conditions that require no signature or have a timelock are
+// rejected, since the signature alone does not prove control of them.
+func VerifyMessageUnlockConditions(uc types.UnlockConditions, message []byte, ms MessageSignature) (types.Address, error) {
+	if uc.SignaturesRequired != 1 {
+		return types.Address{}, fmt.Errorf("unlock conditions require %d signatures, expected 1", uc.SignaturesRequired)
+	} else if uc.Timelock != 0 {
+		return types.Address{}, fmt.Errorf("unlock conditions are timelocked until height %d", uc.Timelock)
+	}
+
+	var found bool
+	for _, key := range uc.PublicKeys {
+		if pk, ok := unlockKeyToPublicKey(key); ok && pk == ms.PublicKey {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return types.Address{}, fmt.Errorf("public key %v is not in the unlock conditions", ms.PublicKey)
+	} else if !ms.PublicKey.VerifyHash(MessageHash(message), ms.Signature) {
+		return types.Address{}, errors.New("invalid signature")
+	}
+	return uc.UnlockHash(), nil
+}
@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+func TestSignMessage(t *testing.T) {
+	seed := testSeed(t)
+	addr := GenerateAddress(seed, 2)
+	message := []byte("I control this address")
+
+	ms := SignMessage(seed, 2, message)
+	if ms.PublicKey != wallet.KeyFromSeed(seed, 2).PublicKey() {
+		t.Fatal("wrong public key")
+	}
+
+	// the text form round trips
+	var parsed MessageSignature
+	if err := parsed.UnmarshalText([]byte(" " + ms.String() + "\n")); err != nil {
+		t.Fatal(err)
+	} else if parsed != ms {
+		t.Fatal("signature does not round trip")
+	}
+
+	if err := VerifyMessage(addr.Address, message, parsed); err != nil {
+		t.Fatal(err)
+	} else if err := VerifyMessage(addr.Address, []byte("something else"), parsed); err == nil {
+		t.Fatal("expected invalid signature")
+	} else if err := VerifyMessage(GenerateAddress(seed, 3).Address, message, parsed); err == nil {
+		t.Fatal("expected address mismatch")
+	}
+
+	// the key's v2 address is also accepted
+	if err := VerifyMessage(GeneratePolicyAddress(seed, 2).Address, message, ms); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := VerifyMessageUnlockConditions(addr.UnlockConditions, message, ms); err != nil {
+		t.Fatal(err)
+	} else if got != addr.Address {
+		t.Fatalf("expected address %v, got %v", addr.Address, got)
+	}
+	multi := addr.UnlockConditions
+	multi.PublicKeys = append(multi.PublicKeys, GenerateAddress(seed, 3).UnlockConditions.PublicKeys...)
+	multi.SignaturesRequired = 2
+	if _, err := VerifyMessageUnlockConditions(multi, message, ms); err == nil {
+		t.Fatal("expected multisig unlock conditions to be rejected")
+	}
+	anyone := addr.UnlockConditions
+	anyone.SignaturesRequired = 0
+	if _, err := VerifyMessageUnlockConditions(anyone, message, ms); err == nil {
+		t.Fatal("expected unlock conditions without signatures to be rejected")
+	}
+	timelocked := addr.UnlockConditions
+	timelocked.Timelock = 100
+	if _, err := VerifyMessageUnlockConditions(timelocked, message, ms); err == nil {
+		t.Fatal("expected timelocked unlock conditions to be rejected")
+	}
+
+	// the signature does not cover the undistinguished hash of the message
+	if ms.PublicKey.VerifyHash(types.HashBytes(message), ms.Signature) {
+		t.Fatal("message hash is not domain separated")
+	}
+
+	for _, bad := range []string{"", ms.PublicKey.String(), "sia-msg-v1:" + ms.PublicKey.String(), "sia-msg-v1:ed25519:00:" + ms.Signature.String()} {
+		if err := parsed.UnmarshalText([]byte(bad)); err == nil {
+			t.Fatalf("expected error parsing %q", bad)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// A Multisig is a v2 address that requires signatures from a threshold of
+// public keys.
+type Multisig struct {
+	Address    types.Address     `json:"address"`
+	Policy     types.SpendPolicy `json:"policy"`
+	Required   uint8             `json:"required"`
+	PublicKeys []types.PublicKey `json:"public_keys"`
+}
+
+// NewMultisig returns the PolicyThreshold address requiring signatures from
+// required of the public keys. The order of the keys changes the address, so
+// every cosigner must use the same order.
+func NewMultisig(required uint8, keys []types.PublicKey) (Multisig, error) {
+	switch {
+	case len(keys) == 0:
+		return Multisig{}, errors.New("no public keys")
+	case len(keys) > 255:
+		return Multisig{}, fmt.Errorf("too many public keys: %d > 255", len(keys))
+	case required == 0 || int(required) > len(keys):
+		return Multisig{}, fmt.Errorf("required signatures must be between 1 and %d", len(keys))
+	}
+
+	seen := make(map[types.PublicKey]bool)
+	policies := make([]types.SpendPolicy, 0, len(keys))
+	for _, pk := range keys {
+		if seen[pk] {
+			return Multisig{}, fmt.Errorf("duplicate public key %v", pk)
+		}
+		seen[pk] = true
+		policies = append(policies, types.PolicyPublicKey(pk))
+	}
+	policy := types.PolicyThreshold(required, policies)
+	return Multisig{
+		Address:    policy.Address(),
+		Policy:     policy,
+		Required:   required,
+		PublicKeys: append([]types.PublicKey(nil), keys...),
+	}, nil
+}
+
+// ExportPublicKey returns the public key at index, to share with cosigners.
+func ExportPublicKey(seed *[32]byte, index uint64) types.PublicKey {
+	return wallet.KeyFromSeed(seed, index).PublicKey()
+}
+
+// thresholdKeys returns the public keys of a threshold policy of public keys.
+// It returns false if the policy is any other kind of policy.
+func thresholdKeys(p types.SpendPolicy) (uint8, []types.PublicKey, bool) {
+	threshold, ok := p.Type.(types.PolicyTypeThreshold)
+	if !ok {
+		return 0, nil, false
+	}
+	keys := make([]types.PublicKey, 0, len(threshold.Of))
+	for _, sp := range threshold.Of {
+		pk, ok := sp.Type.(types.PolicyTypePublicKey)
+		if !ok {
+			return 0, nil, false
+		}
+		keys = append(keys, types.PublicKey(pk))
+	}
+	return threshold.N, keys, true
+}
+
+// addThresholdSignature sets the signature of pk in a partially signed
+// threshold policy. Until it is finalized, a partially signed policy has one
+// signature per key, in the order of the keys, with missing signatures left
+// empty.
+func addThresholdSignature(sp *types.SatisfiedPolicy, pk types.PublicKey, sig types.Signature) (bool, error) {
+	_, keys, ok := thresholdKeys(sp.Policy)
+	if !ok {
+		return false, nil
+	}
+	for i, key := range keys {
+		if key != pk {
+			continue
+		}
+		if len(sp.Signatures) == 0 {
+			sp.Signatures = make([]types.Signature, len(keys))
+		} else if len(sp.Signatures) != len(keys) {
+			return false, errors.New("policy is already finalized")
+		}
+		sp.Signatures[i] = sig
+		return true, nil
+	}
+	return false, nil
+}
+
+// AddMultisigSignature signs every input of txn whose spend policy is a
+// threshold policy including the key at index. Signatures from other
+// cosigners are kept. It returns the number of inputs signed.
+func AddMultisigSignature(cs consensus.State, seed *[32]byte, txn *types.V2Transaction, index uint64) (int, error) {
+	if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return 0, err
+	}
+
+	sk := wallet.KeyFromSeed(seed, index)
+	pk := sk.PublicKey()
+	sig := sk.SignHash(cs.InputSigHash(*txn))
+
+	var signed int
+	for i := range txn.SiacoinInputs {
+		ok, err := addThresholdSignature(&txn.SiacoinInputs[i].SatisfiedPolicy, pk, sig)
+		if err != nil {
+			return 0, fmt.Errorf("siacoin input %d: %w", i, err)
+		} else if ok {
+			signed++
+		}
+	}
+	for i := range txn.SiafundInputs {
+		ok, err := addThresholdSignature(&txn.SiafundInputs[i].SatisfiedPolicy, pk, sig)
+		if err != nil {
+			return 0, fmt.Errorf("siafund input %d: %w", i, err)
+		} else if ok {
+			signed++
+		}
+	}
+	if signed == 0 {
+		return 0, fmt.Errorf("no inputs require a signature from key %d", index)
+	}
+	return signed, nil
+}
+
+// mergeSignatures merges the signatures of two partially signed policies.
+func mergeSignatures(dst *types.SatisfiedPolicy, src types.SatisfiedPolicy) error {
+	if dst.Policy.Address() != src.Policy.Address() {
+		return errors.New("spend policies do not match")
+	} else if len(src.Signatures) == 0 {
+		return nil
+	} else if len(dst.Signatures) == 0 {
+		dst.Signatures = append([]types.Signature(nil), src.Signatures...)
+		return nil
+	} else if len(dst.Signatures) != len(src.Signatures) {
+		return errors.New("cannot merge a finalized policy")
+	}
+
+	for i, sig := range src.Signatures {
+		switch {
+		case sig == (types.Signature{}):
+		case dst.Signatures[i] == (types.Signature{}):
+			dst.Signatures[i] = sig
+		case dst.Signatures[i] != sig:
+			return fmt.Errorf("conflicting signatures for key %d", i)
+		}
+	}
+	return nil
+}
+
+// MergeMultisigSignatures combines partially signed copies of the same
+// transaction from different cosigners.
+func MergeMultisigSignatures(txns ...types.V2Transaction) (types.V2Transaction, error) {
+	if len(txns) == 0 {
+		return types.V2Transaction{}, errors.New("no transactions")
+	}
+
+	merged := txns[0].DeepCopy()
+	id := merged.ID()
+	for i, txn := range txns[1:] {
+		if txn.ID() != id {
+			return types.V2Transaction{}, fmt.Errorf("transaction %d is not a copy of transaction 0", i+1)
+		}
+		for j, sci := range txn.SiacoinInputs {
+			if err := mergeSignatures(&merged.SiacoinInputs[j].SatisfiedPolicy, sci.SatisfiedPolicy); err != nil {
+				return types.V2Transaction{}, fmt.Errorf("transaction %d siacoin input %d: %w", i+1, j, err)
+			}
+		}
+		for j, sfi := range txn.SiafundInputs {
+			if err := mergeSignatures(&merged.SiafundInputs[j].SatisfiedPolicy, sfi.SatisfiedPolicy); err != nil {
+				return types.V2Transaction{}, fmt.Errorf("transaction %d siafund input %d: %w", i+1, j, err)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// finalizeThreshold converts a partially signed threshold policy into the
+// form verified by consensus. Only the first N signatures are used. The keys
+// without a signature are replaced by opaque policies, which do not change
+// the address.
+func finalizeThreshold(sp *types.SatisfiedPolicy) error {
+	_, keys, ok := thresholdKeys(sp.Policy)
+	if !ok {
+		return nil
+	} else if len(sp.Signatures) != len(keys) {
+		return fmt.Errorf("expected %d partial signatures, got %d", len(keys), len(sp.Signatures))
+	}
+
+	sigs := make(map[types.PublicKey]types.Signature)
+	for i, pk := range keys {
+		if sp.Signatures[i] != (types.Signature{}) {
+			sigs[pk] = sp.Signatures[i]
+		}
+	}
+	satisfied, err := satisfyPolicy(sp.Policy, sigs, nil)
+	if err != nil {
+		return err
+	}
+	*sp = satisfied
+	return nil
+}
+
+// FinalizeMultisig converts every partially signed threshold policy in txn
+// into the form verified by consensus. It fails if an input does not have
+// enough signatures. A finalized transaction cannot be merged or signed
+// further.
+func FinalizeMultisig(txn *types.V2Transaction) error {
+	for i := range txn.SiacoinInputs {
+		if err := finalizeThreshold(&txn.SiacoinInputs[i].SatisfiedPolicy); err != nil {
+			return fmt.Errorf("siacoin input %d: %w", i, err)
+		}
+	}
+	for i := range txn.SiafundInputs {
+		if err := finalizeThreshold(&txn.SiafundInputs[i].SatisfiedPolicy); err != nil {
+			return fmt.Errorf("siafund input %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestMultisig(t *testing.T) {
+	seed := testSeed(t)
+	var keys []types.PublicKey
+	for i := uint64(0); i < 3; i++ {
+		keys = append(keys, ExportPublicKey(seed, i))
+	}
+	ms, err := NewMultisig(2, keys)
+	if err != nil {
+		t.Fatal(err)
+	} else if ms.Address != ms.Policy.Address() {
+		t.Fatal("address does not match policy")
+	}
+
+	cs, sces := testChainElements(testState(600000), types.SiacoinOutput{Address: ms.Address, Value: types.Siacoins(10)})
+	txn := types.V2Transaction{
+		SiacoinInputs: []types.V2SiacoinInput{{
+			Parent:          sces[0],
+			SatisfiedPolicy: types.SatisfiedPolicy{Policy: ms.Policy},
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{Address: frand.Entropy256(), Value: types.Siacoins(9)}},
+		MinerFee:       types.Siacoins(1),
+	}
+
+	// the first and last cosigners sign their own copies
+	first, last := txn.DeepCopy(), txn.DeepCopy()
+	if n, err := AddMultisigSignature(cs, seed, &first, 0); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("expected 1 input signed, got %d", n)
+	} else if _, err := AddMultisigSignature(cs, seed, &last, 2); err != nil {
+		t.Fatal(err)
+	} else if _, err := AddMultisigSignature(cs, seed, &last, 3); err == nil {
+		t.Fatal("expected no inputs to sign with a key outside the policy")
+	}
+
+	// one signature does not reach the threshold
+	partial := first.DeepCopy()
+	if err := FinalizeMultisig(&partial); err == nil {
+		t.Fatal("expected threshold error")
+	}
+
+	merged, err := MergeMultisigSignatures(first, last, txn)
+	if err != nil {
+		t.Fatal(err)
+	} else if sigs := merged.SiacoinInputs[0].SatisfiedPolicy.Signatures; sigs[0] == (types.Signature{}) || sigs[1] != (types.Signature{}) || sigs[2] == (types.Signature{}) {
+		t.Fatal("expected signatures from the first and last keys")
+	} else if err := FinalizeMultisig(&merged); err != nil {
+		t.Fatal(err)
+	}
+	if res := ValidateV2Transaction(cs, merged, sces, nil, types.ZeroCurrency); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+
+	// a finalized transaction cannot be merged again
+	if _, err := MergeMultisigSignatures(merged, first); err == nil {
+		t.Fatal("expected finalized merge error")
+	}
+
+	// signatures over a different transaction conflict
+	other := txn.DeepCopy()
+	other.MinerFee = types.Siacoins(2)
+	if _, err := AddMultisigSignature(cs, seed, &other, 0); err != nil {
+		t.Fatal(err)
+	} else if _, err := MergeMultisigSignatures(first, other); err == nil {
+		t.Fatal("expected different transaction error")
+	}
+	other.MinerFee = txn.MinerFee
+	if _, err := MergeMultisigSignatures(first, other); err == nil {
+		t.Fatal("expected conflicting signature error")
+	}
+
+	if _, err := NewMultisig(4, keys); err == nil {
+		t.Fatal("expected required signatures error")
+	} else if _, err := NewMultisig(1, []types.PublicKey{keys[0], keys[0]}); err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+}
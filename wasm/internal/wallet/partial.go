@@ -0,0 +1,449 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// PartialTransactionVersion is the current version of the partial
+// transaction format.
+const PartialTransactionVersion = 1
+
+// partialTransactionMagic prefixes the binary encoding of a partial
+// transaction.
+var partialTransactionMagic = [4]byte{'s', 'p', 't', 'x'}
+
+type (
+	// A PartialInput is the signing state of a transaction input.
+	PartialInput struct {
+		// Indices are hints of the derivation indices of the wallet keys
+		// that can sign the input.
+		Indices    []uint64                            `json:"indices,omitempty"`
+		Signatures map[types.PublicKey]types.Signature `json:"signatures"`
+	}
+
+	// A PartialTransaction is an unsigned or partially signed v2
+	// transaction that can be passed between signers. The transaction's
+	// inputs carry their parent elements and spend policies. Signatures are
+	// collected in the partial inputs until the transaction is finalized.
+	PartialTransaction struct {
+		Version       uint8               `json:"version"`
+		Basis         types.ChainIndex    `json:"basis"`
+		Transaction   types.V2Transaction `json:"transaction"`
+		SiacoinInputs []PartialInput      `json:"siacoin_inputs"`
+		SiafundInputs []PartialInput      `json:"siafund_inputs"`
+	}
+
+	// A PartialInputSummary describes the signing state of an input.
+	PartialInputSummary struct {
+		ParentID types.Hash256  `json:"parent_id"`
+		Address  types.Address  `json:"address"`
+		Siacoins types.Currency `json:"siacoins"`
+		Siafunds uint64         `json:"siafunds"`
+		Policy   string         `json:"policy"`
+		// Signers are the keys that can sign the input and Signed are the
+		// keys that have signed it. Invalid are the keys whose signatures do
+		// not verify; they do not count towards Complete.
+		Signers  []types.PublicKey `json:"signers"`
+		Signed   []types.PublicKey `json:"signed"`
+		Invalid  []types.PublicKey `json:"invalid"`
+		Complete bool              `json:"complete"`
+	}
+
+	// A PartialTransactionSummary describes a partial transaction.
+	PartialTransactionSummary struct {
+		Version        uint8                 `json:"version"`
+		ID             types.TransactionID   `json:"id"`
+		Basis          types.ChainIndex      `json:"basis"`
+		SiacoinInputs  []PartialInputSummary `json:"siacoin_inputs"`
+		SiafundInputs  []PartialInputSummary `json:"siafund_inputs"`
+		SiacoinOutputs []types.SiacoinOutput `json:"siacoin_outputs"`
+		SiafundOutputs []types.SiafundOutput `json:"siafund_outputs"`
+		MinerFee       types.Currency        `json:"miner_fee"`
+		// Complete is true if every input has enough signatures to be
+		// finalized.
+		Complete bool `json:"complete"`
+	}
+)
+
+// validate checks that the partial transaction is well formed.
+func (p PartialTransaction) validate() error {
+	if p.Version != PartialTransactionVersion {
+		return fmt.Errorf("unsupported partial transaction version %d", p.Version)
+	} else if len(p.SiacoinInputs) != len(p.Transaction.SiacoinInputs) {
+		return fmt.Errorf("expected %d partial siacoin inputs, got %d", len(p.Transaction.SiacoinInputs), len(p.SiacoinInputs))
+	} else if len(p.SiafundInputs) != len(p.Transaction.SiafundInputs) {
+		return fmt.Errorf("expected %d partial siafund inputs, got %d", len(p.Transaction.SiafundInputs), len(p.SiafundInputs))
+	}
+	for i, sci := range p.Transaction.SiacoinInputs {
+		if sci.SatisfiedPolicy.Policy.Type == nil {
+			return fmt.Errorf("siacoin input %d has no spend policy", i)
+		}
+	}
+	for i, sfi := range p.Transaction.SiafundInputs {
+		if sfi.SatisfiedPolicy.Policy.Type == nil {
+			return fmt.Errorf("siafund input %d has no spend policy", i)
+		}
+	}
+	return nil
+}
+
+// NewPartialTransaction returns a partial transaction for txn, whose parent
+// elements' proofs are valid at basis. Each input must have its spend policy
+// set. indices optionally contains a derivation index hint for each siacoin
+// input followed by each siafund input, in the same order as
+// SignV2Transaction. Any signatures in txn are removed.
+func NewPartialTransaction(basis types.ChainIndex, txn types.V2Transaction, indices []uint64) (PartialTransaction, error) {
+	if len(indices) != 0 && len(indices) != len(txn.SiacoinInputs)+len(txn.SiafundInputs) {
+		return PartialTransaction{}, fmt.Errorf("expected %d indices, got %d", len(txn.SiacoinInputs)+len(txn.SiafundInputs), len(indices))
+	}
+
+	txn = txn.DeepCopy()
+	p := PartialTransaction{
+		Version:       PartialTransactionVersion,
+		Basis:         basis,
+		Transaction:   txn,
+		SiacoinInputs: make([]PartialInput, len(txn.SiacoinInputs)),
+		SiafundInputs: make([]PartialInput, len(txn.SiafundInputs)),
+	}
+	hint := func(i int) []uint64 {
+		if len(indices) == 0 {
+			return nil
+		}
+		return []uint64{indices[i]}
+	}
+	for i := range txn.SiacoinInputs {
+		txn.SiacoinInputs[i].SatisfiedPolicy.Signatures = nil
+		p.SiacoinInputs[i] = PartialInput{Indices: hint(i), Signatures: make(map[types.PublicKey]types.Signature)}
+	}
+	for i := range txn.SiafundInputs {
+		txn.SiafundInputs[i].SatisfiedPolicy.Signatures = nil
+		p.SiafundInputs[i] = PartialInput{Indices: hint(len(txn.SiacoinInputs) + i), Signatures: make(map[types.PublicKey]types.Signature)}
+	}
+	if err := p.validate(); err != nil {
+		return PartialTransaction{}, err
+	}
+	return p, nil
+}
+
+// partialSigHash returns the hash signed by every input of a partial
+// transaction. The v2 input sig hash does not depend on the chain state, so
+// signatures can be verified without fetching a consensus state.
+func partialSigHash(txn types.V2Transaction) types.Hash256 {
+	var cs consensus.State
+	return cs.InputSigHash(txn)
+}
+
+// verifySignatures returns an error if any signature of the input is not a
+// valid signature of sigHash by one of the policy's keys.
+func verifySignatures(policy types.SpendPolicy, pi PartialInput, sigHash types.Hash256) error {
+	keys := policyKeys(policy)
+	for pk, sig := range pi.Signatures {
+		if !slices.Contains(keys, pk) {
+			return fmt.Errorf("key %v cannot sign the input", pk)
+		} else if !pk.VerifyHash(sigHash, sig) {
+			return fmt.Errorf("invalid signature for key %v", pk)
+		}
+	}
+	return nil
+}
+
+// summarizeInput returns the signing state of an input.
+func summarizeInput(sp types.SatisfiedPolicy, pi PartialInput, sigHash types.Hash256) PartialInputSummary {
+	s := PartialInputSummary{
+		Policy:  sp.Policy.String(),
+		Signers: policyKeys(sp.Policy),
+		Signed:  []types.PublicKey{},
+		Invalid: []types.PublicKey{},
+	}
+	valid := make(map[types.PublicKey]types.Signature)
+	for _, pk := range s.Signers {
+		sig, ok := pi.Signatures[pk]
+		if !ok {
+			continue
+		} else if !pk.VerifyHash(sigHash, sig) {
+			s.Invalid = append(s.Invalid, pk)
+			continue
+		}
+		s.Signed = append(s.Signed, pk)
+		valid[pk] = sig
+	}
+	_, err := satisfyPolicy(sp.Policy, valid, nil)
+	s.Complete = err == nil
+	return s
+}
+
+// InspectPartialTransaction returns a summary of the partial transaction and
+// the signatures it is missing.
+func InspectPartialTransaction(p PartialTransaction) (PartialTransactionSummary, error) {
+	if err := p.validate(); err != nil {
+		return PartialTransactionSummary{}, err
+	}
+
+	txn := p.Transaction
+	sigHash := partialSigHash(txn)
+	summary := PartialTransactionSummary{
+		Version:        p.Version,
+		ID:             txn.ID(),
+		Basis:          p.Basis,
+		SiacoinInputs:  make([]PartialInputSummary, 0, len(txn.SiacoinInputs)),
+		SiafundInputs:  make([]PartialInputSummary, 0, len(txn.SiafundInputs)),
+		SiacoinOutputs: txn.SiacoinOutputs,
+		SiafundOutputs: txn.SiafundOutputs,
+		MinerFee:       txn.MinerFee,
+		Complete:       true,
+	}
+	for i, sci := range txn.SiacoinInputs {
+		s := summarizeInput(sci.SatisfiedPolicy, p.SiacoinInputs[i], sigHash)
+		s.ParentID = types.Hash256(sci.Parent.ID)
+		s.Address = sci.Parent.SiacoinOutput.Address
+		s.Siacoins = sci.Parent.SiacoinOutput.Value
+		summary.SiacoinInputs = append(summary.SiacoinInputs, s)
+		summary.Complete = summary.Complete && s.Complete
+	}
+	for i, sfi := range txn.SiafundInputs {
+		s := summarizeInput(sfi.SatisfiedPolicy, p.SiafundInputs[i], sigHash)
+		s.ParentID = types.Hash256(sfi.Parent.ID)
+		s.Address = sfi.Parent.SiafundOutput.Address
+		s.Siafunds = sfi.Parent.SiafundOutput.Value
+		summary.SiafundInputs = append(summary.SiafundInputs, s)
+		summary.Complete = summary.Complete && s.Complete
+	}
+	return summary, nil
+}
+
+// SignPartialTransaction adds a signature to each input that can be signed
+// by the keys at the input's index hints or at any of indices. It returns the
+// number of signatures added.
+func SignPartialTransaction(cs consensus.State, seed *[32]byte, p *PartialTransaction, indices []uint64) (int, error) {
+	if err := p.validate(); err != nil {
+		return 0, err
+	} else if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return 0, err
+	}
+
+	sigHash := cs.InputSigHash(p.Transaction)
+	var signed int
+	sign := func(policy types.SpendPolicy, pi *PartialInput) {
+		if pi.Signatures == nil {
+			pi.Signatures = make(map[types.PublicKey]types.Signature)
+		}
+		keys := policyKeys(policy)
+		for _, index := range append(slices.Clone(pi.Indices), indices...) {
+			sk := wallet.KeyFromSeed(seed, index)
+			if pk := sk.PublicKey(); slices.Contains(keys, pk) {
+				if _, ok := pi.Signatures[pk]; !ok {
+					signed++
+				}
+				pi.Signatures[pk] = sk.SignHash(sigHash)
+			}
+		}
+	}
+	for i, sci := range p.Transaction.SiacoinInputs {
+		sign(sci.SatisfiedPolicy.Policy, &p.SiacoinInputs[i])
+	}
+	for i, sfi := range p.Transaction.SiafundInputs {
+		sign(sfi.SatisfiedPolicy.Policy, &p.SiafundInputs[i])
+	}
+	if signed == 0 {
+		return 0, errors.New("no inputs can be signed by the seed")
+	}
+	return signed, nil
+}
+
+// combineInputs merges the index hints and signatures of src into dst.
+func combineInputs(dst *PartialInput, src PartialInput) error {
+	for _, index := range src.Indices {
+		if !slices.Contains(dst.Indices, index) {
+			dst.Indices = append(dst.Indices, index)
+		}
+	}
+	for pk, sig := range src.Signatures {
+		if existing, ok := dst.Signatures[pk]; ok && existing != sig {
+			return fmt.Errorf("conflicting signatures for key %v", pk)
+		}
+		dst.Signatures[pk] = sig
+	}
+	return nil
+}
+
+// CombinePartialTransactions merges the signatures of partial transactions
+// for the same transaction and basis. Every signature is verified before it
+// is merged.
+func CombinePartialTransactions(ps ...PartialTransaction) (PartialTransaction, error) {
+	if len(ps) == 0 {
+		return PartialTransaction{}, errors.New("no partial transactions")
+	}
+	for i, p := range ps {
+		if err := p.validate(); err != nil {
+			return PartialTransaction{}, fmt.Errorf("partial transaction %d: %w", i, err)
+		}
+	}
+
+	combined, err := NewPartialTransaction(ps[0].Basis, ps[0].Transaction, nil)
+	if err != nil {
+		return PartialTransaction{}, err
+	}
+	id := combined.Transaction.ID()
+	sigHash := partialSigHash(combined.Transaction)
+	for i, p := range ps {
+		if p.Transaction.ID() != id {
+			return PartialTransaction{}, fmt.Errorf("partial transaction %d is for a different transaction", i)
+		} else if p.Basis != combined.Basis {
+			return PartialTransaction{}, fmt.Errorf("partial transaction %d has basis %v, expected %v", i, p.Basis, combined.Basis)
+		}
+		for j, sci := range combined.Transaction.SiacoinInputs {
+			if err := verifySignatures(sci.SatisfiedPolicy.Policy, p.SiacoinInputs[j], sigHash); err != nil {
+				return PartialTransaction{}, fmt.Errorf("partial transaction %d siacoin input %d: %w", i, j, err)
+			} else if err := combineInputs(&combined.SiacoinInputs[j], p.SiacoinInputs[j]); err != nil {
+				return PartialTransaction{}, fmt.Errorf("partial transaction %d siacoin input %d: %w", i, j, err)
+			}
+		}
+		for j, sfi := range combined.Transaction.SiafundInputs {
+			if err := verifySignatures(sfi.SatisfiedPolicy.Policy, p.SiafundInputs[j], sigHash); err != nil {
+				return PartialTransaction{}, fmt.Errorf("partial transaction %d siafund input %d: %w", i, j, err)
+			} else if err := combineInputs(&combined.SiafundInputs[j], p.SiafundInputs[j]); err != nil {
+				return PartialTransaction{}, fmt.Errorf("partial transaction %d siafund input %d: %w", i, j, err)
+			}
+		}
+	}
+	return combined, nil
+}
+
+// FinalizePartialTransaction returns the transaction with each input's
+// spend policy satisfied by the collected signatures. It fails if an input
+// is missing signatures or has an invalid signature.
+func FinalizePartialTransaction(p PartialTransaction) (types.V2Transaction, error) {
+	if err := p.validate(); err != nil {
+		return types.V2Transaction{}, err
+	}
+
+	txn := p.Transaction.DeepCopy()
+	sigHash := partialSigHash(txn)
+	for i := range txn.SiacoinInputs {
+		if err := verifySignatures(txn.SiacoinInputs[i].SatisfiedPolicy.Policy, p.SiacoinInputs[i], sigHash); err != nil {
+			return types.V2Transaction{}, fmt.Errorf("siacoin input %d: %w", i, err)
+		}
+		sp, err := satisfyPolicy(txn.SiacoinInputs[i].SatisfiedPolicy.Policy, p.SiacoinInputs[i].Signatures, nil)
+		if err != nil {
+			return types.V2Transaction{}, fmt.Errorf("siacoin input %d: %w", i, err)
+		}
+		txn.SiacoinInputs[i].SatisfiedPolicy = sp
+	}
+	for i := range txn.SiafundInputs {
+		if err := verifySignatures(txn.SiafundInputs[i].SatisfiedPolicy.Policy, p.SiafundInputs[i], sigHash); err != nil {
+			return types.V2Transaction{}, fmt.Errorf("siafund input %d: %w", i, err)
+		}
+		sp, err := satisfyPolicy(txn.SiafundInputs[i].SatisfiedPolicy.Policy, p.SiafundInputs[i].Signatures, nil)
+		if err != nil {
+			return types.V2Transaction{}, fmt.Errorf("siafund input %d: %w", i, err)
+		}
+		txn.SiafundInputs[i].SatisfiedPolicy = sp
+	}
+	return txn, nil
+}
+
+// A partialSignature is a signature and the key that made it. Signatures are
+// encoded as a slice sorted by key so the encoding is deterministic.
+type partialSignature struct {
+	PublicKey types.PublicKey
+	Signature types.Signature
+}
+
+// EncodeTo implements types.EncoderTo.
+func (pi PartialInput) EncodeTo(e *types.Encoder) {
+	types.EncodeSliceFn(e, pi.Indices, func(e *types.Encoder, index uint64) {
+		e.WriteUint64(index)
+	})
+
+	sigs := make([]partialSignature, 0, len(pi.Signatures))
+	for pk, sig := range pi.Signatures {
+		sigs = append(sigs, partialSignature{pk, sig})
+	}
+	slices.SortFunc(sigs, func(a, b partialSignature) int { return bytes.Compare(a.PublicKey[:], b.PublicKey[:]) })
+	types.EncodeSliceFn(e, sigs, func(e *types.Encoder, ps partialSignature) {
+		ps.PublicKey.EncodeTo(e)
+		ps.Signature.EncodeTo(e)
+	})
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (pi *PartialInput) DecodeFrom(d *types.Decoder) {
+	types.DecodeSliceFn(d, &pi.Indices, func(d *types.Decoder) uint64 {
+		return d.ReadUint64()
+	})
+
+	var sigs []partialSignature
+	types.DecodeSliceFn(d, &sigs, func(d *types.Decoder) (ps partialSignature) {
+		ps.PublicKey.DecodeFrom(d)
+		ps.Signature.DecodeFrom(d)
+		return
+	})
+	pi.Signatures = make(map[types.PublicKey]types.Signature, len(sigs))
+	for _, ps := range sigs {
+		pi.Signatures[ps.PublicKey] = ps.Signature
+	}
+}
+
+// EncodeTo implements types.EncoderTo.
+func (p PartialTransaction) EncodeTo(e *types.Encoder) {
+	e.Write(partialTransactionMagic[:])
+	e.WriteUint8(p.Version)
+	p.Basis.EncodeTo(e)
+	p.Transaction.EncodeTo(e)
+	types.EncodeSlice(e, p.SiacoinInputs)
+	types.EncodeSlice(e, p.SiafundInputs)
+}
+
+// DecodeFrom implements types.DecoderFrom.
+func (p *PartialTransaction) DecodeFrom(d *types.Decoder) {
+	var magic [4]byte
+	d.Read(magic[:])
+	if magic != partialTransactionMagic {
+		d.SetErr(errors.New("not a partial transaction"))
+		return
+	}
+	p.Version = d.ReadUint8()
+	if p.Version != PartialTransactionVersion {
+		d.SetErr(fmt.Errorf("unsupported partial transaction version %d", p.Version))
+		return
+	}
+	p.Basis.DecodeFrom(d)
+	p.Transaction.DecodeFrom(d)
+	types.DecodeSlice(d, &p.SiacoinInputs)
+	types.DecodeSlice(d, &p.SiafundInputs)
+}
+
+// EncodePartialTransaction returns the binary encoding of a partial
+// transaction, suitable for a file or QR code.
+func EncodePartialTransaction(p PartialTransaction) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := types.NewEncoder(buf)
+	p.EncodeTo(enc)
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodePartialTransaction decodes a partial transaction encoded by
+// EncodePartialTransaction.
+func DecodePartialTransaction(buf []byte) (PartialTransaction, error) {
+	var p PartialTransaction
+	if err := decodeExact(buf, &p); err != nil {
+		return PartialTransaction{}, fmt.Errorf("error decoding partial transaction: %w", err)
+	} else if err := p.validate(); err != nil {
+		return PartialTransaction{}, err
+	}
+	return p, nil
+}
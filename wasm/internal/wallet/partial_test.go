@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestPartialTransaction(t *testing.T) {
+	seed := testSeed(t)
+	cosignerSeed := (*[32]byte)(frand.Bytes(32))
+
+	standard := GenerateAddress(seed, 1)
+	ms, err := NewMultisig(2, []types.PublicKey{
+		ExportPublicKey(seed, 0),
+		ExportPublicKey(cosignerSeed, 5),
+		ExportPublicKey(seed, 2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, sces := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: standard.Address, Value: types.Siacoins(10)},
+		types.SiacoinOutput{Address: ms.Address, Value: types.Siacoins(20)},
+	)
+	txn := types.V2Transaction{
+		SiacoinInputs: []types.V2SiacoinInput{
+			{Parent: sces[0], SatisfiedPolicy: types.SatisfiedPolicy{Policy: standard.spendPolicy()}},
+			{Parent: sces[1], SatisfiedPolicy: types.SatisfiedPolicy{Policy: ms.Policy}},
+		},
+		SiacoinOutputs: []types.SiacoinOutput{{Address: frand.Entropy256(), Value: types.Siacoins(29)}},
+		MinerFee:       types.Siacoins(1),
+	}
+
+	p, err := NewPartialTransaction(cs.Index, txn, []uint64{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cosigner receives an encoded copy and signs with their own key
+	buf, err := EncodePartialTransaction(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigned, err := DecodePartialTransaction(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if cosigned.Transaction.ID() != txn.ID() {
+		t.Fatal("decoded transaction does not match")
+	}
+	if n, err := SignPartialTransaction(cs, cosignerSeed, &cosigned, []uint64{5}); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("expected 1 signature, got %d", n)
+	}
+
+	// the wallet signs both inputs with the first multisig key
+	if n, err := SignPartialTransaction(cs, seed, &p, nil); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("expected 2 signatures, got %d", n)
+	}
+	summary, err := InspectPartialTransaction(p)
+	if err != nil {
+		t.Fatal(err)
+	} else if summary.Complete || !summary.SiacoinInputs[0].Complete || summary.SiacoinInputs[1].Complete {
+		t.Fatal("expected only the standard input to be complete")
+	} else if len(summary.SiacoinInputs[1].Signers) != 3 || len(summary.SiacoinInputs[1].Signed) != 1 {
+		t.Fatalf("unexpected multisig signers %v", summary.SiacoinInputs[1])
+	} else if _, err := FinalizePartialTransaction(p); err == nil {
+		t.Fatal("expected missing signature error")
+	}
+
+	// combine a JSON round tripped copy
+	buf, err = json.Marshal(cosigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded PartialTransaction
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	combined, err := CombinePartialTransactions(p, decoded)
+	if err != nil {
+		t.Fatal(err)
+	} else if summary, err := InspectPartialTransaction(combined); err != nil {
+		t.Fatal(err)
+	} else if !summary.Complete {
+		t.Fatal("expected combined transaction to be complete")
+	}
+
+	final, err := FinalizePartialTransaction(combined)
+	if err != nil {
+		t.Fatal(err)
+	} else if res := ValidateV2Transaction(cs, final, sces, nil, types.ZeroCurrency); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+
+	// a garbage signature is reported and refused
+	forged := combined
+	forged.SiacoinInputs = append([]PartialInput(nil), combined.SiacoinInputs...)
+	forged.SiacoinInputs[1].Signatures = maps.Clone(combined.SiacoinInputs[1].Signatures)
+	cosignerKey := ExportPublicKey(cosignerSeed, 5)
+	forged.SiacoinInputs[1].Signatures[cosignerKey] = types.Signature(frand.Bytes(64))
+	if summary, err := InspectPartialTransaction(forged); err != nil {
+		t.Fatal(err)
+	} else if summary.Complete || len(summary.SiacoinInputs[1].Invalid) != 1 || summary.SiacoinInputs[1].Invalid[0] != cosignerKey {
+		t.Fatalf("expected the forged signature to be invalid, got %+v", summary.SiacoinInputs[1])
+	} else if _, err := FinalizePartialTransaction(forged); err == nil {
+		t.Fatal("expected invalid signature error")
+	} else if _, err := CombinePartialTransactions(p, forged); err == nil {
+		t.Fatal("expected invalid signature error")
+	}
+
+	// partial transactions with different bases cannot be combined
+	rebased := cosigned
+	rebased.Basis.Height++
+	if _, err := CombinePartialTransactions(p, rebased); err == nil {
+		t.Fatal("expected different basis error")
+	}
+
+	// partial transactions for different transactions cannot be combined
+	txn.MinerFee = types.Siacoins(2)
+	other, err := NewPartialTransaction(cs.Index, txn, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := CombinePartialTransactions(p, other); err == nil {
+		t.Fatal("expected different transaction error")
+	}
+
+	if _, err := DecodePartialTransaction([]byte("not a partial transaction")); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
@@ -0,0 +1,300 @@
+package wallet
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/bip39"
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+	"go.sia.tech/walletd/v2/wallet"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSuggestions is the number of dictionary matches suggested for an unknown
+// word.
+const maxSuggestions = 3
+
+type (
+	// An UnknownWord is a word of a recovery phrase that is not in the
+	// dictionary of the phrase's format.
+	UnknownWord struct {
+		Index int    `json:"index"`
+		Word  string `json:"word"`
+		// Suggestions are the closest dictionary words, closest first.
+		Suggestions []string `json:"suggestions"`
+	}
+
+	// A PhraseValidation describes the problems with a recovery phrase.
+	PhraseValidation struct {
+		Valid bool `json:"valid"`
+		// Format is SeedTypeSia or SeedTypeWalrus. It is empty if the format
+		// could not be detected.
+		Format string `json:"format"`
+		// Language is the dictionary language of a siad phrase.
+		Language  string `json:"language,omitempty"`
+		WordCount int    `json:"word_count"`
+		// Normalized is the phrase with its words lower cased, NFC
+		// normalized and separated by single spaces. Known words are
+		// replaced by their dictionary spelling.
+		Normalized     string        `json:"normalized"`
+		UnknownWords   []UnknownWord `json:"unknown_words"`
+		ChecksumFailed bool          `json:"checksum_failed"`
+		Message        string        `json:"message,omitempty"`
+	}
+)
+
+// splitPhrase splits a phrase into lower case, NFC normalized words. Any
+// whitespace or comma separates words and invisible formatting characters are
+// removed.
+func splitPhrase(phrase string) []string {
+	words := strings.FieldsFunc(phrase, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	for i, w := range words {
+		w = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Cf, r) {
+				return -1
+			}
+			return r
+		}, w)
+		words[i] = strings.ToLower(norm.NFC.String(w))
+	}
+	return slices.DeleteFunc(words, func(w string) bool { return w == "" })
+}
+
+// stripAccents removes the diacritics from the Latin letters of a word.
+// Combining marks of other scripts, such as the Japanese dakuten, are kept
+// since they change the letter.
+func stripAccents(word string) string {
+	var latin bool
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		if !unicode.Is(unicode.Mn, r) {
+			latin = unicode.Is(unicode.Latin, r)
+			return r
+		} else if latin {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(word)))
+}
+
+// A phraseDict is the dictionary of a seed type and, for siad phrases, a
+// language.
+type phraseDict struct {
+	seedType string
+	language siad.Language
+	words    []string
+	lookup   func(string) (int, bool)
+	// unaccented maps the lower case words without diacritics to their
+	// index
+	unaccented map[string]int
+}
+
+// phraseDicts returns the dictionaries of the supported phrase formats,
+// English siad first.
+var phraseDicts = sync.OnceValue(func() []phraseDict {
+	var dicts []phraseDict
+	for _, lang := range siad.Languages() {
+		words, err := siad.Words(lang)
+		if err != nil {
+			panic(err) // should never happen
+		}
+		dicts = append(dicts, phraseDict{
+			seedType: SeedTypeSia,
+			language: lang,
+			words:    words,
+			lookup:   func(w string) (int, bool) { return siad.WordIndex(lang, w) },
+		})
+	}
+	dicts = append(dicts, phraseDict{
+		seedType: SeedTypeWalrus,
+		words:    bip39.Words(),
+		lookup:   bip39.WordIndex,
+	})
+
+	for i, d := range dicts {
+		dicts[i].unaccented = make(map[string]int, len(d.words))
+		for j, w := range d.words {
+			dicts[i].unaccented[stripAccents(strings.ToLower(w))] = j
+		}
+	}
+	return dicts
+})
+
+// lookupWord returns the dictionary index of a normalized word. If it is
+// not found, the word is retried without diacritics and then compared to
+// the dictionary words without diacritics.
+func (d phraseDict) lookupWord(word string) (int, bool) {
+	if i, ok := d.lookup(word); ok {
+		return i, true
+	}
+	unaccented := stripAccents(word)
+	if i, ok := d.lookup(unaccented); ok {
+		return i, true
+	}
+	i, ok := d.unaccented[unaccented]
+	return i, ok
+}
+
+// known returns the number of words in the dictionary.
+func (d phraseDict) known(words []string) (n int) {
+	for _, w := range words {
+		if _, ok := d.lookupWord(w); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// detectFormat returns the dictionary of the words. The seed type is chosen
+// by the word count, or by the closest count if it is wrong, and the
+// dictionary that contains the most words is used. ok is false if the count
+// is wrong and no word is in the dictionary.
+func detectFormat(words []string) (best phraseDict, ok bool) {
+	seedType := SeedTypeSia
+	if len(words) < 20 {
+		seedType = SeedTypeWalrus
+	}
+
+	bestKnown := -1
+	for _, d := range phraseDicts() {
+		if d.seedType != seedType {
+			continue
+		} else if n := d.known(words); n > bestKnown {
+			best, bestKnown = d, n
+		}
+	}
+	switch len(words) {
+	case 12, 28, 29:
+		return best, true
+	default:
+		return best, bestKnown > 0
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of rune insertions, deletions, substitutions and adjacent
+// transpositions needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// suggestWords returns the n dictionary words closest to word. Case and
+// diacritics are ignored.
+func suggestWords(dict []string, word string, n int) []string {
+	word = stripAccents(word)
+	type candidate struct {
+		word     string
+		distance int
+	}
+	candidates := make([]candidate, len(dict))
+	for i, w := range dict {
+		candidates[i] = candidate{w, editDistance(word, stripAccents(strings.ToLower(w)))}
+	}
+	// the sort is stable so ties keep dictionary order
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return a.distance - b.distance
+	})
+
+	suggestions := make([]string, 0, n)
+	for _, c := range candidates[:min(n, len(candidates))] {
+		suggestions = append(suggestions, c.word)
+	}
+	return suggestions
+}
+
+// normalizeWords replaces each known word with its dictionary spelling and
+// returns the indices of the unknown words.
+func (d phraseDict) normalizeWords(words []string) (normalized []string, unknown []int) {
+	normalized = make([]string, len(words))
+	for i, w := range words {
+		j, ok := d.lookupWord(w)
+		if !ok {
+			normalized[i] = w
+			unknown = append(unknown, i)
+			continue
+		}
+		normalized[i] = d.words[j]
+	}
+	return normalized, unknown
+}
+
+// seedFromPhrase derives the seed of a phrase with every word in the
+// dictionary.
+func (d phraseDict) seedFromPhrase(seed *[32]byte, phrase string) error {
+	if d.seedType == SeedTypeSia {
+		return siad.SeedFromPhraseLanguage(seed, phrase, d.language)
+	}
+	return wallet.SeedFromPhrase(seed, phrase)
+}
+
+// ValidatePhrase checks a recovery phrase and reports its format, unknown
+// words with suggested corrections and whether its checksum fails.
+func ValidatePhrase(phrase string) PhraseValidation {
+	words := splitPhrase(phrase)
+	res := PhraseValidation{
+		WordCount:    len(words),
+		Normalized:   strings.Join(words, " "),
+		UnknownWords: []UnknownWord{},
+	}
+	dict, ok := detectFormat(words)
+	if !ok {
+		res.Message = fmt.Sprintf("expected 12, 28 or 29 words, got %d", len(words))
+		return res
+	}
+	res.Format, res.Language = dict.seedType, string(dict.language)
+
+	normalized, unknown := dict.normalizeWords(words)
+	res.Normalized = strings.Join(normalized, " ")
+	for _, i := range unknown {
+		res.UnknownWords = append(res.UnknownWords, UnknownWord{
+			Index:       i,
+			Word:        words[i],
+			Suggestions: suggestWords(dict.words, words[i], maxSuggestions),
+		})
+	}
+
+	switch {
+	case res.Format == SeedTypeWalrus && len(words) != 12:
+		res.Message = fmt.Sprintf("expected 12 words, got %d", len(words))
+	case res.Format == SeedTypeSia && len(words) != 28 && len(words) != 29:
+		res.Message = fmt.Sprintf("expected 28 or 29 words, got %d", len(words))
+	case len(unknown) != 0:
+		res.Message = fmt.Sprintf("%d unknown words", len(unknown))
+	default:
+		// the checksum can only be checked once every word is known
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := dict.seedFromPhrase(&seed, res.Normalized); err != nil {
+			res.ChecksumFailed = true
+			res.Message = "checksum does not match, a word may be wrong or out of order"
+			return res
+		}
+		res.Valid = true
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// testGermanPhrase is testPhrase's seed in the german siad dictionary.
+const testGermanPhrase = "Nestbau Biotop Fortgang Gesetz Vollblut Habicht Logik Labor Weißbier Unruhe polieren Argument Geige treffen Nessel bewirken Detektiv Kiosk besorgen Donner Defekt verhexen Limonade Bescheid begrüßen beizen Fügung Bikini"
+
+func TestValidatePhrase(t *testing.T) {
+	walrus := wallet.NewSeedPhrase()
+
+	tests := []struct {
+		name     string
+		phrase   string
+		format   string
+		valid    bool
+		checksum bool
+		unknown  []int
+	}{
+		{name: "sia", phrase: testPhrase, format: SeedTypeSia, valid: true},
+		{name: "walrus", phrase: walrus, format: SeedTypeWalrus, valid: true},
+		{name: "formatting", phrase: " " + strings.ToUpper(strings.ReplaceAll(testPhrase, " ", ",\n\t ")) + "​ ", format: SeedTypeSia, valid: true},
+		{name: "accents", phrase: strings.Replace(testPhrase, "rodent", "rödent", 1), format: SeedTypeSia, valid: true},
+		{name: "german", phrase: strings.ToLower(testGermanPhrase), format: SeedTypeSia, valid: true},
+		{name: "german accents", phrase: strings.Replace(testGermanPhrase, "Fügung", "Fugung", 1), format: SeedTypeSia, valid: true},
+		{name: "typo", phrase: strings.Replace(testPhrase, "colony", "cloony", 1), format: SeedTypeSia, unknown: []int{1}},
+		{name: "walrus typo", phrase: "abandonn" + strings.TrimPrefix(walrus, strings.Fields(walrus)[0]), format: SeedTypeWalrus, unknown: []int{0}},
+		{name: "checksum", phrase: strings.Replace(testPhrase, "rodent", "colony", 1), format: SeedTypeSia, checksum: true},
+		{name: "short", phrase: strings.Join(strings.Fields(testPhrase)[:20], " "), format: SeedTypeSia},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ValidatePhrase(tt.phrase)
+			switch {
+			case res.Format != tt.format:
+				t.Fatalf("expected format %q, got %q", tt.format, res.Format)
+			case res.Valid != tt.valid:
+				t.Fatalf("expected valid %v, got %v (%s)", tt.valid, res.Valid, res.Message)
+			case res.ChecksumFailed != tt.checksum:
+				t.Fatalf("expected checksum failed %v, got %v", tt.checksum, res.ChecksumFailed)
+			case len(res.UnknownWords) != len(tt.unknown):
+				t.Fatalf("expected %d unknown words, got %d", len(tt.unknown), len(res.UnknownWords))
+			}
+			for i, uw := range res.UnknownWords {
+				if uw.Index != tt.unknown[i] {
+					t.Fatalf("expected unknown word at %d, got %d", tt.unknown[i], uw.Index)
+				} else if len(uw.Suggestions) == 0 {
+					t.Fatalf("expected suggestions for %q", uw.Word)
+				}
+			}
+
+			if res.Valid {
+				var a, b [32]byte
+				if err := PhraseToSeed(tt.phrase, &a); err != nil {
+					t.Fatal(err)
+				} else if err := PhraseToSeed(res.Normalized, &b); err != nil {
+					t.Fatal(err)
+				} else if a != b {
+					t.Fatal("normalized phrase derives a different seed")
+				}
+			}
+		})
+	}
+
+	var a, b [32]byte
+	if err := PhraseToSeed(testPhrase, &a); err != nil {
+		t.Fatal(err)
+	} else if err := PhraseToSeed(testGermanPhrase, &b); err != nil {
+		t.Fatal(err)
+	} else if a != b {
+		t.Fatal("german phrase derives a different seed")
+	} else if res := ValidatePhrase(testGermanPhrase); res.Language != "german" {
+		t.Fatalf("expected german, got %q", res.Language)
+	}
+
+	// a wrong german word fails the german checksum, as ValidatePhrase reports
+	swapped := strings.Replace(testGermanPhrase, "Nestbau Biotop", "Biotop Nestbau", 1)
+	if err := PhraseToSeed(swapped, &b); !errors.Is(err, siad.ErrChecksum) {
+		t.Fatalf("expected checksum error, got %v", err)
+	} else if res := ValidatePhrase(swapped); !res.ChecksumFailed {
+		t.Fatal("expected ValidatePhrase to report a checksum failure")
+	}
+
+	res := ValidatePhrase(strings.Replace(testPhrase, "colony", "cloony", 1))
+	if !slices.Contains(res.UnknownWords[0].Suggestions, "colony") {
+		t.Fatalf("expected colony to be suggested, got %v", res.UnknownWords[0].Suggestions)
+	}
+}
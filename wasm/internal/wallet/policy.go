@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"fmt"
+
+	"go.sia.tech/core/types"
+)
+
+// unlockKeyToPublicKey returns the ed25519 public key of an unlock key.
+func unlockKeyToPublicKey(uk types.UnlockKey) (types.PublicKey, bool) {
+	var pk types.PublicKey
+	if uk.Algorithm != types.SpecifierEd25519 || len(uk.Key) != len(pk) {
+		return types.PublicKey{}, false
+	}
+	copy(pk[:], uk.Key)
+	return pk, true
+}
+
+// keyPolicy returns the spend policy of the key's v1 or v2 address,
+// whichever matches addr.
+func keyPolicy(pk types.PublicKey, addr types.Address) (types.SpendPolicy, bool) {
+	switch addr {
+	case types.StandardUnlockHash(pk):
+		return types.SpendPolicy{Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(pk))}, true
+	case types.PolicyPublicKey(pk).Address():
+		return types.PolicyPublicKey(pk), true
+	default:
+		return types.SpendPolicy{}, false
+	}
+}
+
+// policyKeys returns the public keys that can sign for a spend policy, in the
+// order their signatures are verified.
+func policyKeys(p types.SpendPolicy) []types.PublicKey {
+	switch p := p.Type.(type) {
+	case types.PolicyTypePublicKey:
+		return []types.PublicKey{types.PublicKey(p)}
+	case types.PolicyTypeThreshold:
+		var keys []types.PublicKey
+		for _, sp := range p.Of {
+			keys = append(keys, policyKeys(sp)...)
+		}
+		return keys
+	case types.PolicyTypeUnlockConditions:
+		var keys []types.PublicKey
+		for _, uk := range p.PublicKeys {
+			if pk, ok := unlockKeyToPublicKey(uk); ok {
+				keys = append(keys, pk)
+			}
+		}
+		return keys
+	default:
+		return nil
+	}
+}
+
+// satisfyPolicy returns the satisfied form of p using the signatures in
+// sigs and the preimages in preimages, keyed by their SHA-256 hash. Threshold
+// policies are satisfied by their first N satisfiable sub-policies and the
+// rest are made opaque. Time locks are assumed to have passed; consensus
+// checks them when the transaction is validated.
+func satisfyPolicy(p types.SpendPolicy, sigs map[types.PublicKey]types.Signature, preimages map[types.Hash256][32]byte) (types.SatisfiedPolicy, error) {
+	var satisfy func(types.SpendPolicy) (types.SatisfiedPolicy, error)
+	satisfy = func(p types.SpendPolicy) (types.SatisfiedPolicy, error) {
+		switch pt := p.Type.(type) {
+		case types.PolicyTypeAbove, types.PolicyTypeAfter:
+			return types.SatisfiedPolicy{Policy: p}, nil
+		case types.PolicyTypePublicKey:
+			sig, ok := sigs[types.PublicKey(pt)]
+			if !ok {
+				return types.SatisfiedPolicy{}, fmt.Errorf("missing signature for key %v", types.PublicKey(pt))
+			}
+			return types.SatisfiedPolicy{Policy: p, Signatures: []types.Signature{sig}}, nil
+		case types.PolicyTypeHash:
+			preimage, ok := preimages[types.Hash256(pt)]
+			if !ok {
+				return types.SatisfiedPolicy{}, fmt.Errorf("missing preimage for hash %v", types.Hash256(pt))
+			}
+			return types.SatisfiedPolicy{Policy: p, Preimages: [][32]byte{preimage}}, nil
+		case types.PolicyTypeThreshold:
+			var satisfied types.SatisfiedPolicy
+			of := make([]types.SpendPolicy, 0, len(pt.Of))
+			var n uint8
+			for _, sp := range pt.Of {
+				if _, ok := sp.Type.(types.PolicyTypeOpaque); ok {
+					of = append(of, sp)
+					continue
+				} else if n == pt.N {
+					of = append(of, types.PolicyOpaque(sp))
+					continue
+				}
+				ssp, err := satisfy(sp)
+				if err != nil {
+					of = append(of, types.PolicyOpaque(sp))
+					continue
+				}
+				of = append(of, ssp.Policy)
+				satisfied.Signatures = append(satisfied.Signatures, ssp.Signatures...)
+				satisfied.Preimages = append(satisfied.Preimages, ssp.Preimages...)
+				n++
+			}
+			if n < pt.N {
+				return types.SatisfiedPolicy{}, fmt.Errorf("threshold not reached: %d of %d", n, pt.N)
+			}
+			satisfied.Policy = types.PolicyThreshold(pt.N, of)
+			return satisfied, nil
+		case types.PolicyTypeUnlockConditions:
+			var signatures []types.Signature
+			for _, uk := range pt.PublicKeys {
+				if uint64(len(signatures)) == pt.SignaturesRequired {
+					break
+				} else if pk, ok := unlockKeyToPublicKey(uk); ok {
+					if sig, ok := sigs[pk]; ok {
+						signatures = append(signatures, sig)
+					}
+				}
+			}
+			if uint64(len(signatures)) < pt.SignaturesRequired {
+				return types.SatisfiedPolicy{}, fmt.Errorf("missing signatures: %d of %d", len(signatures), pt.SignaturesRequired)
+			}
+			return types.SatisfiedPolicy{Policy: p, Signatures: signatures}, nil
+		default:
+			return types.SatisfiedPolicy{}, fmt.Errorf("cannot satisfy %T policy", pt)
+		}
+	}
+	return satisfy(p)
+}
@@ -0,0 +1,259 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+)
+
+// Repair kinds describe the edit that turns a damaged phrase into a
+// candidate.
+const (
+	RepairMissing = "missing"
+	RepairWrong   = "wrong"
+	RepairSwapped = "swapped"
+)
+
+const (
+	// repairProgressInterval is the number of candidates checked between
+	// progress reports.
+	repairProgressInterval = 1000
+	// maxRepairConfirmAddresses is the maximum number of keys of each
+	// candidate whose addresses can be checked on chain.
+	maxRepairConfirmAddresses = 100
+)
+
+type (
+	// A RepairCandidate is a phrase with a valid checksum that differs from
+	// a damaged phrase by one edit.
+	RepairCandidate struct {
+		Phrase string `json:"phrase"`
+		Kind   string `json:"kind"`
+		// Index is the position of the inserted or replaced word, or of the
+		// first word of the swapped pair.
+		Index int `json:"index"`
+		// Word is the inserted or replacement word. It is empty for swaps.
+		Word string `json:"word,omitempty"`
+		// Used is true if one of the candidate's first addresses has been
+		// seen on chain. It is only set when candidates are confirmed.
+		Used bool `json:"used"`
+	}
+
+	// RepairOptions configures RepairPhrase.
+	RepairOptions struct {
+		// ConfirmAddresses is the number of keys of each candidate whose
+		// addresses are checked on chain. Zero skips the check. It can be at
+		// most 100.
+		ConfirmAddresses uint64 `json:"confirm_addresses"`
+	}
+
+	// RepairProgress reports the progress of RepairPhrase.
+	RepairProgress struct {
+		Checked    int `json:"checked"`
+		Total      int `json:"total"`
+		Candidates int `json:"candidates"`
+	}
+
+	// a repairEdit is a single edit of a damaged phrase.
+	repairEdit struct {
+		kind  string
+		index int
+		word  int // dictionary index, unused for swaps
+	}
+)
+
+// ErrRepairNotPossible is returned when a phrase has more damage than
+// RepairPhrase can fix.
+var ErrRepairNotPossible = errors.New("phrase cannot be repaired")
+
+// repairEdits returns the edits to try on n words of the given format. If
+// unknown is not negative, only replacements of that word are tried.
+func repairEdits(seedType string, n, unknown, dictSize int) ([]repairEdit, error) {
+	var missing, complete bool
+	switch seedType {
+	case SeedTypeSia:
+		// a 28 word phrase may be complete or a 29 word phrase missing a
+		// word
+		missing = n == 27 || n == 28
+		complete = n == 28 || n == 29
+	case SeedTypeWalrus:
+		missing = n == 11
+		complete = n == 12
+	}
+
+	var edits []repairEdit
+	switch {
+	case unknown >= 0 && complete:
+		for w := range dictSize {
+			edits = append(edits, repairEdit{RepairWrong, unknown, w})
+		}
+		return edits, nil
+	case unknown >= 0:
+		return nil, fmt.Errorf("%w: a word is unknown and the phrase has %d words", ErrRepairNotPossible, n)
+	case !missing && !complete:
+		return nil, fmt.Errorf("%w: %d words is not one word from a %s phrase", ErrRepairNotPossible, n, seedType)
+	}
+
+	if missing {
+		for i := range n + 1 {
+			for w := range dictSize {
+				edits = append(edits, repairEdit{RepairMissing, i, w})
+			}
+		}
+	}
+	if complete {
+		for i := range n {
+			for w := range dictSize {
+				edits = append(edits, repairEdit{RepairWrong, i, w})
+			}
+		}
+		for i := range n - 1 {
+			edits = append(edits, repairEdit{RepairSwapped, i, -1})
+		}
+	}
+	return edits, nil
+}
+
+// apply returns the words with the edit applied.
+func (e repairEdit) apply(words, dict []string) []string {
+	switch e.kind {
+	case RepairMissing:
+		return slices.Insert(slices.Clone(words), e.index, dict[e.word])
+	case RepairWrong:
+		edited := slices.Clone(words)
+		edited[e.index] = dict[e.word]
+		return edited
+	case RepairSwapped:
+		edited := slices.Clone(words)
+		edited[e.index], edited[e.index+1] = edited[e.index+1], edited[e.index]
+		return edited
+	default:
+		panic(fmt.Sprintf("unknown repair kind %q", e.kind)) // should never happen
+	}
+}
+
+// confirmCandidates marks the candidates with a used address among their
+// first n keys. Candidates are checked together and split only when one of
+// them is used.
+func confirmCandidates(ctx context.Context, b backend.Backend, candidates []RepairCandidate, n uint64) error {
+	addresses := make([][]types.Address, len(candidates))
+	for i, c := range candidates {
+		var seed [32]byte
+		if err := PhraseToSeed(c.Phrase, &seed); err != nil {
+			return fmt.Errorf("failed to derive seed of candidate %d: %w", i, err)
+		}
+		for j := range n {
+			addresses[i] = append(addresses[i], GenerateAddress(&seed, j).Address, GeneratePolicyAddress(&seed, j).Address)
+		}
+		clear(seed[:])
+	}
+
+	used, err := findUsedGroups(ctx, b, addresses)
+	if err != nil {
+		return err
+	}
+	for i := range candidates {
+		candidates[i].Used = used[i]
+	}
+	return nil
+}
+
+// RepairPhrase tries every phrase that differs from a damaged phrase by one
+// missing word, one wrong word or one swapped pair of adjacent words and
+// returns those with a valid checksum. If opts.ConfirmAddresses is set, each
+// candidate's first addresses are checked on chain with b and used
+// candidates are sorted first. progress is called periodically and the
+// search stops when ctx is cancelled.
+//
+// A siad phrase has a 48-bit checksum, so a candidate is almost certainly
+// the original phrase. A 12-word phrase only has a 4-bit checksum and many
+// candidates pass; confirming them on chain is the only way to tell them
+// apart.
+func RepairPhrase(ctx context.Context, b backend.Backend, phrase string, opts RepairOptions, progress func(RepairProgress)) ([]RepairCandidate, error) {
+	words := splitPhrase(phrase)
+	dict, ok := detectFormat(words)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to detect the format of a %d word phrase", ErrRepairNotPossible, len(words))
+	}
+	if opts.ConfirmAddresses > maxRepairConfirmAddresses {
+		return nil, fmt.Errorf("at most %d keys of each candidate can be confirmed, got %d", maxRepairConfirmAddresses, opts.ConfirmAddresses)
+	} else if opts.ConfirmAddresses > 0 && b == nil {
+		return nil, errors.New("a backend is required to confirm candidates")
+	}
+
+	words, unknown := dict.normalizeWords(words)
+	if len(unknown) > 1 {
+		return nil, fmt.Errorf("%w: %d words are unknown", ErrRepairNotPossible, len(unknown))
+	}
+	unknownIndex := -1
+	if len(unknown) == 1 {
+		unknownIndex = unknown[0]
+	}
+
+	edits, err := repairEdits(dict.seedType, len(words), unknownIndex, len(dict.words))
+	if err != nil {
+		return nil, err
+	}
+
+	var seed [32]byte
+	defer clear(seed[:])
+	// the original phrase is skipped so a replacement with the same word is
+	// not reported
+	seen := map[string]bool{strings.Join(words, " "): true}
+	candidates := []RepairCandidate{}
+	for i, edit := range edits {
+		if i%repairProgressInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if i != 0 && progress != nil {
+				progress(RepairProgress{Checked: i, Total: len(edits), Candidates: len(candidates)})
+			}
+		}
+
+		candidate := strings.Join(edit.apply(words, dict.words), " ")
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		if err := dict.seedFromPhrase(&seed, candidate); err != nil {
+			continue
+		}
+
+		c := RepairCandidate{
+			Phrase: candidate,
+			Kind:   edit.kind,
+			Index:  edit.index,
+		}
+		if edit.kind != RepairSwapped {
+			c.Word = dict.words[edit.word]
+		}
+		candidates = append(candidates, c)
+	}
+	if progress != nil {
+		progress(RepairProgress{Checked: len(edits), Total: len(edits), Candidates: len(candidates)})
+	}
+
+	if opts.ConfirmAddresses > 0 {
+		if err := confirmCandidates(ctx, b, candidates, opts.ConfirmAddresses); err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(candidates, func(a, b RepairCandidate) int {
+			switch {
+			case a.Used == b.Used:
+				return 0
+			case a.Used:
+				return -1
+			default:
+				return 1
+			}
+		})
+	}
+	return candidates, nil
+}
@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+// testWalrusPhrase is a 12-word phrase without repeated adjacent words.
+const testWalrusPhrase = "swap exit toward gold system wild rail stuff rocket tomorrow assume record"
+
+func TestRepairPhrase(t *testing.T) {
+	// replace returns the phrase with the word at i replaced
+	replace := func(phrase string, i int, word string) string {
+		words := strings.Fields(phrase)
+		words[i] = word
+		return strings.Join(words, " ")
+	}
+	words := strings.Fields(testWalrusPhrase)
+	swapped := slices.Clone(words)
+	swapped[4], swapped[5] = swapped[5], swapped[4]
+
+	tests := []struct {
+		name     string
+		original string
+		damaged  string
+		kind     string
+		index    int
+	}{
+		{"missing", testWalrusPhrase, strings.Join(slices.Delete(slices.Clone(words), 7, 8), " "), RepairMissing, 7},
+		{"wrong", testWalrusPhrase, replace(testWalrusPhrase, 3, "cactus"), RepairWrong, 3},
+		{"swapped", testWalrusPhrase, strings.Join(swapped, " "), RepairSwapped, 4},
+		// siad matches words by prefix, so candidates use the dictionary
+		// spelling
+		{"sia unknown", ValidatePhrase(testPhrase).Normalized, replace(testPhrase, 3, "xyzzy"), RepairWrong, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reports int
+			candidates, err := RepairPhrase(context.Background(), nil, tt.damaged, RepairOptions{}, func(RepairProgress) { reports++ })
+			if err != nil {
+				t.Fatal(err)
+			} else if reports == 0 {
+				t.Fatal("expected progress")
+			}
+			i := slices.IndexFunc(candidates, func(c RepairCandidate) bool { return c.Phrase == tt.original })
+			if i == -1 {
+				t.Fatalf("original phrase not found in %d candidates", len(candidates))
+			} else if candidates[i].Kind != tt.kind || candidates[i].Index != tt.index {
+				t.Fatalf("expected %s at %d, got %s at %d", tt.kind, tt.index, candidates[i].Kind, candidates[i].Index)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := RepairPhrase(ctx, nil, strings.Join(swapped, " "), RepairOptions{}, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("not possible", func(t *testing.T) {
+		damaged := replace(replace(testPhrase, 1, "qqqqq"), 2, "zzzzz")
+		_, err := RepairPhrase(context.Background(), nil, damaged, RepairOptions{}, nil)
+		if !errors.Is(err, ErrRepairNotPossible) {
+			t.Fatalf("expected ErrRepairNotPossible, got %v", err)
+		}
+	})
+}
+
+func TestRepairPhraseConfirm(t *testing.T) {
+	phrase := testWalrusPhrase
+	var seed [32]byte
+	if err := PhraseToSeed(phrase, &seed); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &countingBackend{Memory: backend.NewMemory(testState(1000))}
+	b.AddSiacoinElements(types.SiacoinElement{
+		ID: frand.Entropy256(),
+		SiacoinOutput: types.SiacoinOutput{
+			Address: GeneratePolicyAddress(&seed, 2).Address,
+			Value:   types.Siacoins(1),
+		},
+	})
+
+	words := strings.Fields(phrase)
+	damaged := strings.Join(slices.Delete(words, 5, 6), " ")
+	candidates, err := RepairPhrase(context.Background(), b, damaged, RepairOptions{ConfirmAddresses: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(candidates) < 2 {
+		// the 4-bit checksum of a 12-word phrase passes many candidates
+		t.Fatalf("expected multiple candidates, got %d", len(candidates))
+	} else if !candidates[0].Used || candidates[0].Phrase != phrase {
+		t.Fatalf("expected the used original phrase first, got %+v", candidates[0])
+	} else if candidates[1].Used {
+		t.Fatal("expected only one used candidate")
+	} else if b.largest > maxCheckAddresses {
+		t.Fatalf("expected at most %d addresses per request, got %d", maxCheckAddresses, b.largest)
+	}
+
+	if _, err := RepairPhrase(context.Background(), b, damaged, RepairOptions{ConfirmAddresses: maxRepairConfirmAddresses + 1}, nil); err == nil {
+		t.Fatal("expected too many addresses error")
+	}
+}
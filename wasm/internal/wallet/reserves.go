@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+type (
+	// A ReserveSignature proves control of an address by signing the
+	// proof's challenge with the address's key.
+	ReserveSignature struct {
+		Address   types.Address   `json:"address"`
+		PublicKey types.PublicKey `json:"public_key"`
+		Signature types.Signature `json:"signature"`
+	}
+
+	// A ReserveProof shows that a wallet controls the listed unspent outputs
+	// at a chain index. It is checked with VerifyReserveProof against the
+	// consensus state at Index.
+	ReserveProof struct {
+		Challenge       string                 `json:"challenge"`
+		Index           types.ChainIndex       `json:"index"`
+		Siacoins        types.Currency         `json:"siacoins"`
+		Siafunds        uint64                 `json:"siafunds"`
+		SiacoinElements []types.SiacoinElement `json:"siacoin_elements"`
+		SiafundElements []types.SiafundElement `json:"siafund_elements"`
+		Signatures      []ReserveSignature     `json:"signatures"`
+	}
+)
+
+// ReserveProofHash returns the hash signed by each address in a reserve
+// proof.
+func ReserveProofHash(challenge string, index types.ChainIndex, addr types.Address) types.Hash256 {
+	h := types.NewHasher()
+	h.WriteDistinguisher("reserves")
+	h.E.WriteString(challenge)
+	index.EncodeTo(h.E)
+	addr.EncodeTo(h.E)
+	return h.Sum()
+}
+
+// ReserveElements returns the confirmed unspent siacoin and siafund elements
+// of the addresses and the chain index their proofs are valid at.
+func ReserveElements(w backend.Backend, addresses []types.Address) (types.ChainIndex, []types.SiacoinElement, []types.SiafundElement, error) {
+	return unspentElements(w, addresses, true)
+}
+
+// NewReserveProof signs the challenge with the key of every address owning
+// one of the elements. The elements' proofs must be valid at index.
+func NewReserveProof(seed *[32]byte, addresses []Address, challenge string, index types.ChainIndex, sces []types.SiacoinElement, sfes []types.SiafundElement) (ReserveProof, error) {
+	indices := make(map[types.Address]uint64, len(addresses))
+	for _, addr := range addresses {
+		indices[addr.Address] = addr.Index
+	}
+
+	p := ReserveProof{
+		Challenge:       challenge,
+		Index:           index,
+		SiacoinElements: make([]types.SiacoinElement, 0, len(sces)),
+		SiafundElements: make([]types.SiafundElement, 0, len(sfes)),
+	}
+	signed := make(map[types.Address]bool)
+	sign := func(addr types.Address) error {
+		if signed[addr] {
+			return nil
+		}
+		i, ok := indices[addr]
+		if !ok {
+			return fmt.Errorf("address %v is not in the wallet", addr)
+		}
+		sk := wallet.KeyFromSeed(seed, i)
+		defer clear(sk)
+		if !keyOwnsAddress(sk.PublicKey(), addr) {
+			return fmt.Errorf("address %v is not key %d of the seed", addr, i)
+		}
+		p.Signatures = append(p.Signatures, ReserveSignature{
+			Address:   addr,
+			PublicKey: sk.PublicKey(),
+			Signature: sk.SignHash(ReserveProofHash(challenge, index, addr)),
+		})
+		signed[addr] = true
+		return nil
+	}
+
+	for _, sce := range sces {
+		if err := sign(sce.SiacoinOutput.Address); err != nil {
+			return ReserveProof{}, err
+		}
+		p.SiacoinElements = append(p.SiacoinElements, sce.Copy())
+		p.Siacoins = p.Siacoins.Add(sce.SiacoinOutput.Value)
+	}
+	for _, sfe := range sfes {
+		if err := sign(sfe.SiafundOutput.Address); err != nil {
+			return ReserveProof{}, err
+		}
+		p.SiafundElements = append(p.SiafundElements, sfe.Copy())
+		p.Siafunds += sfe.SiafundOutput.Value
+	}
+	return p, nil
+}
+
+// ProveReserves returns a reserve proof for the wallet's unspent outputs at
+// the current tip.
+func ProveReserves(w backend.Backend, seed *[32]byte, addresses []Address, challenge string) (ReserveProof, error) {
+	unlockHashes := make([]types.Address, 0, len(addresses))
+	for _, addr := range addresses {
+		unlockHashes = append(unlockHashes, addr.Address)
+	}
+	index, sces, sfes, err := ReserveElements(w, unlockHashes)
+	if err != nil {
+		return ReserveProof{}, err
+	}
+	return NewReserveProof(seed, addresses, challenge, index, sces, sfes)
+}
+
+// VerifyReserveProof checks that every element in the proof was unspent in
+// cs, that each owning address signed the challenge and that the stated
+// totals match the elements. cs must be the state at the proof's index.
+func VerifyReserveProof(cs consensus.State, challenge string, p ReserveProof) error {
+	if p.Challenge != challenge {
+		return fmt.Errorf("proof is for challenge %q, expected %q", p.Challenge, challenge)
+	} else if cs.Index != p.Index {
+		return fmt.Errorf("proof is for chain index %v, state is at %v", p.Index, cs.Index)
+	}
+
+	owners := make(map[types.Address]bool, len(p.Signatures))
+	for _, rs := range p.Signatures {
+		if !keyOwnsAddress(rs.PublicKey, rs.Address) {
+			return fmt.Errorf("public key %v does not belong to address %v", rs.PublicKey, rs.Address)
+		} else if !rs.PublicKey.VerifyHash(ReserveProofHash(p.Challenge, p.Index, rs.Address), rs.Signature) {
+			return fmt.Errorf("invalid signature for address %v", rs.Address)
+		}
+		owners[rs.Address] = true
+	}
+
+	// the elements are checked as the parents of a transaction to reuse the
+	// accumulator's proof verification
+	var txn types.V2Transaction
+	var siacoins types.Currency
+	var siafunds uint64
+	seenSC := make(map[types.SiacoinOutputID]bool)
+	for _, sce := range p.SiacoinElements {
+		if seenSC[sce.ID] {
+			return fmt.Errorf("siacoin output %v is listed twice", sce.ID)
+		} else if !owners[sce.SiacoinOutput.Address] {
+			return fmt.Errorf("siacoin output %v is not signed for by %v", sce.ID, sce.SiacoinOutput.Address)
+		}
+		seenSC[sce.ID] = true
+		// the values are untrusted until the elements are validated
+		var overflow bool
+		siacoins, overflow = siacoins.AddWithOverflow(sce.SiacoinOutput.Value)
+		if overflow {
+			return errors.New("siacoin outputs overflow")
+		}
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{Parent: sce})
+	}
+	seenSF := make(map[types.SiafundOutputID]bool)
+	for _, sfe := range p.SiafundElements {
+		if seenSF[sfe.ID] {
+			return fmt.Errorf("siafund output %v is listed twice", sfe.ID)
+		} else if !owners[sfe.SiafundOutput.Address] {
+			return fmt.Errorf("siafund output %v is not signed for by %v", sfe.ID, sfe.SiafundOutput.Address)
+		}
+		seenSF[sfe.ID] = true
+		var carry uint64
+		siafunds, carry = bits.Add64(siafunds, sfe.SiafundOutput.Value, 0)
+		if carry != 0 {
+			return errors.New("siafund outputs overflow")
+		}
+		txn.SiafundInputs = append(txn.SiafundInputs, types.V2SiafundInput{Parent: sfe})
+	}
+	if err := cs.Elements.ValidateTransactionElements(txn); err != nil {
+		return fmt.Errorf("outputs are not unspent at %v: %w", p.Index, err)
+	}
+
+	if !siacoins.Equals(p.Siacoins) {
+		return fmt.Errorf("stated siacoin total %v does not match outputs %v", p.Siacoins, siacoins)
+	} else if siafunds != p.Siafunds {
+		return fmt.Errorf("stated siafund total %d does not match outputs %d", p.Siafunds, siafunds)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestReserveProof(t *testing.T) {
+	seed := testSeed(t)
+	addresses := GenerateAddresses(seed, 0, 3)
+
+	cs, sces := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: addresses[0].Address, Value: types.Siacoins(10)},
+		types.SiacoinOutput{Address: addresses[0].Address, Value: types.Siacoins(5)},
+		types.SiacoinOutput{Address: addresses[2].Address, Value: types.Siacoins(20)},
+	)
+	b := backend.NewMemory(cs)
+	b.AddSiacoinElements(sces...)
+
+	const challenge = "audit 2026-Q3"
+	p, err := ProveReserves(b, seed, addresses, challenge)
+	if err != nil {
+		t.Fatal(err)
+	} else if p.Index != cs.Index {
+		t.Fatalf("expected proof at %v, got %v", cs.Index, p.Index)
+	} else if !p.Siacoins.Equals(types.Siacoins(35)) || len(p.SiacoinElements) != 3 {
+		t.Fatalf("expected 35 SC in 3 outputs, got %v in %d", p.Siacoins, len(p.SiacoinElements))
+	} else if len(p.Signatures) != 2 {
+		t.Fatalf("expected one signature per owning address, got %d", len(p.Signatures))
+	} else if err := VerifyReserveProof(cs, challenge, p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyReserveProof(cs, "audit 2026-Q4", p); err == nil {
+		t.Fatal("expected challenge mismatch")
+	}
+
+	inflated := p
+	inflated.Siacoins = types.Siacoins(100)
+	if err := VerifyReserveProof(cs, challenge, inflated); err == nil {
+		t.Fatal("expected total mismatch")
+	}
+
+	// an output with a modified value does not match the accumulator
+	tampered := p
+	tampered.SiacoinElements = append([]types.SiacoinElement(nil), p.SiacoinElements...)
+	tampered.SiacoinElements[0].SiacoinOutput.Value = types.Siacoins(1000)
+	tampered.Siacoins = tampered.Siacoins.Add(types.Siacoins(1000)).Sub(p.SiacoinElements[0].SiacoinOutput.Value)
+	if err := VerifyReserveProof(cs, challenge, tampered); err == nil {
+		t.Fatal("expected invalid element proof")
+	}
+
+	// values that overflow the total are rejected instead of panicking
+	overflowing := p
+	overflowing.SiacoinElements = append([]types.SiacoinElement(nil), p.SiacoinElements...)
+	overflowing.SiacoinElements[0].SiacoinOutput.Value = types.MaxCurrency
+	if err := VerifyReserveProof(cs, challenge, overflowing); err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+	overflowing = p
+	overflowing.SiafundElements = []types.SiafundElement{
+		{ID: frand.Entropy256(), SiafundOutput: types.SiafundOutput{Address: addresses[0].Address, Value: math.MaxUint64}},
+		{ID: frand.Entropy256(), SiafundOutput: types.SiafundOutput{Address: addresses[0].Address, Value: 1}},
+	}
+	if err := VerifyReserveProof(cs, challenge, overflowing); err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+
+	// a signature for one address cannot be reused for another
+	forged := p
+	forged.Signatures = append([]ReserveSignature(nil), p.Signatures...)
+	forged.Signatures[0].Signature = forged.Signatures[1].Signature
+	if err := VerifyReserveProof(cs, challenge, forged); err == nil {
+		t.Fatal("expected invalid signature")
+	}
+
+	// outputs of addresses outside the wallet cannot be proven
+	if _, err := NewReserveProof(seed, addresses[:1], challenge, cs.Index, sces, nil); err == nil {
+		t.Fatal("expected unknown address error")
+	}
+}
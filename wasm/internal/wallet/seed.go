@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+const (
+	// SeedTypeWalrus is a 12-word BIP39 recovery phrase.
+	SeedTypeWalrus = "walrus"
+	// SeedTypeSia is a 28/29 word siad recovery phrase.
+	SeedTypeSia = "sia"
+)
+
+// NewSeedPhrase generates a new recovery phrase of the given seed type.
+func NewSeedPhrase(seedType string) (string, error) {
+	switch seedType {
+	case SeedTypeWalrus:
+		return wallet.NewSeedPhrase(), nil
+	case SeedTypeSia:
+		return siad.NewSeedPhrase(), nil
+	default:
+		return "", fmt.Errorf("unknown seed type: %q", seedType)
+	}
+}
+
+// PhraseToSeed derives a 32-byte seed from either a 12-word or a 28/29 word
+// siad recovery phrase. The phrase is normalized as by ValidatePhrase and the
+// language of a siad phrase is detected.
+func PhraseToSeed(phrase string, seed *[32]byte) error {
+	words := splitPhrase(phrase)
+	switch len(words) {
+	case 12, 28, 29:
+	default:
+		return fmt.Errorf("invalid seed phrase length: %d words", len(words))
+	}
+
+	// derive the seed with the dictionary ValidatePhrase reports, so the
+	// errors of both agree
+	dict, _ := detectFormat(words)
+	normalized, _ := dict.normalizeWords(words)
+	return dict.seedFromPhrase(seed, strings.Join(normalized, " "))
+}
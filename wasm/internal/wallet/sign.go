@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// SignTransaction signs the first len(indices) signatures of a v1
+// transaction. Each signature is signed with the key at the corresponding
+// index. v1 transactions cannot be signed after the v2 require height.
+func SignTransaction(cs consensus.State, seed *[32]byte, txn *types.Transaction, indices []uint64) error {
+	if len(indices) > len(txn.Signatures) {
+		return fmt.Errorf("expected at most %d signatures, got %d", len(txn.Signatures), len(indices))
+	}
+
+	sigIndices := make(map[int]uint64, len(indices))
+	for i, index := range indices {
+		sigIndices[i] = index
+	}
+	return signV1(cs, seed, txn, sigIndices)
+}
+
+// signV1 signs each signature of a v1 transaction in indices with the key at
+// its index. Each signature must cover the whole transaction and its public
+// key index must name the key in the unlock conditions of its input. Nothing
+// is signed if any signature does not match.
+func signV1(cs consensus.State, seed *[32]byte, txn *types.Transaction, indices map[int]uint64) error {
+	if err := CheckTransactionFormat(cs, FormatV1); err != nil {
+		return err
+	}
+
+	parents := make(map[types.Hash256]types.UnlockConditions)
+	for _, sci := range txn.SiacoinInputs {
+		parents[types.Hash256(sci.ParentID)] = sci.UnlockConditions
+	}
+	for _, sfi := range txn.SiafundInputs {
+		parents[types.Hash256(sfi.ParentID)] = sfi.UnlockConditions
+	}
+
+	// check every signature before modifying the transaction
+	for i, index := range indices {
+		sig := txn.Signatures[i]
+		if !sig.CoveredFields.WholeTransaction {
+			return fmt.Errorf("signature %d (%v): only signatures covering the whole transaction can be signed", i, sig.ParentID)
+		}
+		uc, ok := parents[sig.ParentID]
+		if !ok {
+			continue
+		} else if sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+			return fmt.Errorf("signature %d (%v): public key index %d is out of range", i, sig.ParentID, sig.PublicKeyIndex)
+		}
+		pk, ok := unlockKeyToPublicKey(uc.PublicKeys[sig.PublicKeyIndex])
+		if !ok || pk != wallet.KeyFromSeed(seed, index).PublicKey() {
+			return fmt.Errorf("signature %d (%v): public key %d of the unlock conditions does not belong to key %d", i, sig.ParentID, sig.PublicKeyIndex, index)
+		}
+	}
+
+	// signatures that cover other signatures are signed last, so the
+	// signatures they cover are already set
+	order := slices.Sorted(maps.Keys(indices))
+	slices.SortStableFunc(order, func(a, b int) int {
+		return len(txn.Signatures[a].CoveredFields.Signatures) - len(txn.Signatures[b].CoveredFields.Signatures)
+	})
+	for _, i := range order {
+		sig := &txn.Signatures[i]
+		sigHash := cs.WholeSigHash(*txn, sig.ParentID, sig.PublicKeyIndex, sig.Timelock, sig.CoveredFields.Signatures)
+		sk := wallet.KeyFromSeed(seed, indices[i])
+		signature := sk.SignHash(sigHash)
+		sig.Signature = signature[:]
+	}
+	return nil
+}
+
+// SignV2Transaction signs each siacoin and siafund input of a v2
+// transaction. indices contains the key index for each siacoin input followed
+// by each siafund input. Each input's parent address must be the v1 or v2
+// address of its key; the input's spend policy is set to match. No input is
+// signed if any of them does not match its key. v2 transactions cannot be
+// signed before the v2 allow height.
+func SignV2Transaction(cs consensus.State, seed *[32]byte, txn *types.V2Transaction, indices []uint64) error {
+	if len(indices) != len(txn.SiacoinInputs)+len(txn.SiafundInputs) {
+		return fmt.Errorf("expected %d signatures, got %d", len(txn.SiacoinInputs)+len(txn.SiafundInputs), len(indices))
+	}
+
+	scIndices := make(map[int]uint64, len(txn.SiacoinInputs))
+	for i := range txn.SiacoinInputs {
+		scIndices[i] = indices[i]
+	}
+	sfIndices := make(map[int]uint64, len(txn.SiafundInputs))
+	for i := range txn.SiafundInputs {
+		sfIndices[i] = indices[len(txn.SiacoinInputs)+i]
+	}
+	return signV2(cs, seed, txn, scIndices, sfIndices)
+}
+
+// signV2 signs each siacoin and siafund input of a v2 transaction in
+// scIndices and sfIndices with the key at its index. No input is signed if
+// any of them does not match its key.
+func signV2(cs consensus.State, seed *[32]byte, txn *types.V2Transaction, scIndices, sfIndices map[int]uint64) error {
+	if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return err
+	}
+
+	type signer struct {
+		key    types.PrivateKey
+		policy types.SpendPolicy
+	}
+	derive := func(index uint64, addr types.Address, input string) (signer, error) {
+		sk := wallet.KeyFromSeed(seed, index)
+		policy, ok := keyPolicy(sk.PublicKey(), addr)
+		if !ok {
+			clear(sk)
+			return signer{}, fmt.Errorf("%s: parent address %v does not belong to key %d", input, addr, index)
+		}
+		return signer{sk, policy}, nil
+	}
+
+	// check every input before modifying the transaction
+	scSigners := make(map[int]signer, len(scIndices))
+	sfSigners := make(map[int]signer, len(sfIndices))
+	defer func() {
+		for _, s := range scSigners {
+			clear(s.key)
+		}
+		for _, s := range sfSigners {
+			clear(s.key)
+		}
+	}()
+	for i, sci := range txn.SiacoinInputs {
+		index, ok := scIndices[i]
+		if !ok {
+			continue
+		}
+		s, err := derive(index, sci.Parent.SiacoinOutput.Address, fmt.Sprintf("siacoin input %d (%v)", i, sci.Parent.ID))
+		if err != nil {
+			return err
+		}
+		scSigners[i] = s
+	}
+	for i, sfi := range txn.SiafundInputs {
+		index, ok := sfIndices[i]
+		if !ok {
+			continue
+		}
+		s, err := derive(index, sfi.Parent.SiafundOutput.Address, fmt.Sprintf("siafund input %d (%v)", i, sfi.Parent.ID))
+		if err != nil {
+			return err
+		}
+		sfSigners[i] = s
+	}
+
+	// the signature hash does not cover the satisfied policies
+	sigHash := cs.InputSigHash(*txn)
+	for i, s := range scSigners {
+		txn.SiacoinInputs[i].SatisfiedPolicy = types.SatisfiedPolicy{
+			Policy:     s.policy,
+			Signatures: []types.Signature{s.key.SignHash(sigHash)},
+		}
+	}
+	for i, s := range sfSigners {
+		txn.SiafundInputs[i].SatisfiedPolicy = types.SatisfiedPolicy{
+			Policy:     s.policy,
+			Signatures: []types.Signature{s.key.SignHash(sigHash)},
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+func TestSignTransaction(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(500000)
+
+	indices := []uint64{3, 1}
+	var txn types.Transaction
+	for _, index := range indices {
+		pk := wallet.KeyFromSeed(seed, index).PublicKey()
+		id := types.SiacoinOutputID(frand.Entropy256())
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         id,
+			UnlockConditions: types.StandardUnlockConditions(pk),
+		})
+		txn.Signatures = append(txn.Signatures, types.TransactionSignature{
+			ParentID:      types.Hash256(id),
+			CoveredFields: types.CoveredFields{WholeTransaction: true},
+		})
+	}
+	txn.SiacoinOutputs = []types.SiacoinOutput{{Address: types.VoidAddress, Value: types.Siacoins(1)}}
+
+	if err := SignTransaction(cs, seed, &txn, indices); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, index := range indices {
+		pk := wallet.KeyFromSeed(seed, index).PublicKey()
+		sigHash := cs.WholeSigHash(txn, txn.Signatures[i].ParentID, 0, 0, nil)
+		if !pk.VerifyHash(sigHash, types.Signature(txn.Signatures[i].Signature)) {
+			t.Fatalf("invalid signature %d", i)
+		}
+	}
+
+	if err := SignTransaction(cs, seed, &txn, []uint64{0, 1, 2}); err == nil {
+		t.Fatal("expected too many indices error")
+	}
+}
+
+func TestSignTransactionUnlockConditions(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(500000)
+
+	pk := wallet.KeyFromSeed(seed, 2).PublicKey()
+	other := wallet.KeyFromSeed(seed, 7).PublicKey()
+	tests := []struct {
+		uc  types.UnlockConditions
+		sig types.TransactionSignature
+	}{
+		// a timelocked input signed with a timelocked signature
+		{
+			types.UnlockConditions{PublicKeys: []types.UnlockKey{pk.UnlockKey()}, SignaturesRequired: 1, Timelock: 100},
+			types.TransactionSignature{Timelock: 50, CoveredFields: types.CoveredFields{WholeTransaction: true}},
+		},
+		// the second key of a multisig input, covering the first signature
+		{
+			types.UnlockConditions{PublicKeys: []types.UnlockKey{other.UnlockKey(), pk.UnlockKey()}, SignaturesRequired: 2},
+			types.TransactionSignature{PublicKeyIndex: 1, CoveredFields: types.CoveredFields{WholeTransaction: true, Signatures: []uint64{0}}},
+		},
+	}
+	for _, test := range tests {
+		id := types.SiacoinOutputID(frand.Entropy256())
+		sig := test.sig
+		sig.ParentID = types.Hash256(id)
+		txn := types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{ParentID: id, UnlockConditions: test.uc}},
+			Signatures: []types.TransactionSignature{
+				{ParentID: types.Hash256(id), CoveredFields: types.CoveredFields{WholeTransaction: true}, Signature: frand.Bytes(64)},
+				sig,
+			},
+		}
+		// the first signature is made by another signer
+		indices := map[int]uint64{1: 2}
+		if err := signV1(cs, seed, &txn, indices); err != nil {
+			t.Fatal(err)
+		}
+		// consensus hashes each signature with its own key index, timelock
+		// and covered signatures
+		signed := txn.Signatures[1]
+		sigHash := cs.WholeSigHash(txn, signed.ParentID, signed.PublicKeyIndex, signed.Timelock, signed.CoveredFields.Signatures)
+		if !pk.VerifyHash(sigHash, types.Signature(signed.Signature)) {
+			t.Fatal("invalid signature")
+		}
+
+		// a key that is not at the signature's public key index is refused
+		if err := signV1(cs, seed, &txn, map[int]uint64{1: 7}); err == nil {
+			t.Fatal("expected key mismatch error")
+		}
+	}
+
+	// signatures that do not cover the whole transaction are refused
+	id := types.SiacoinOutputID(frand.Entropy256())
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: id, UnlockConditions: types.StandardUnlockConditions(pk)}},
+		Signatures:    []types.TransactionSignature{{ParentID: types.Hash256(id), CoveredFields: types.CoveredFields{SiacoinInputs: []uint64{0}}}},
+	}
+	if err := SignTransaction(cs, seed, &txn, []uint64{2}); err == nil {
+		t.Fatal("expected covered fields error")
+	}
+}
+
+func TestSignV2Transaction(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(600000)
+
+	indices := []uint64{5, 0, 2}
+	var txn types.V2Transaction
+	for _, index := range indices[:2] {
+		pk := wallet.KeyFromSeed(seed, index).PublicKey()
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{
+			Parent: types.SiacoinElement{
+				ID:            frand.Entropy256(),
+				SiacoinOutput: types.SiacoinOutput{Address: types.StandardUnlockHash(pk), Value: types.Siacoins(1)},
+			},
+			SatisfiedPolicy: types.SatisfiedPolicy{
+				Policy: types.SpendPolicy{Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(pk))},
+			},
+		})
+	}
+	pk := wallet.KeyFromSeed(seed, indices[2]).PublicKey()
+	txn.SiafundInputs = append(txn.SiafundInputs, types.V2SiafundInput{
+		Parent: types.SiafundElement{
+			ID:            frand.Entropy256(),
+			SiafundOutput: types.SiafundOutput{Address: types.StandardUnlockHash(pk), Value: 1},
+		},
+		SatisfiedPolicy: types.SatisfiedPolicy{
+			Policy: types.SpendPolicy{Type: types.PolicyTypeUnlockConditions(types.StandardUnlockConditions(pk))},
+		},
+	})
+
+	if err := SignV2Transaction(cs, seed, &txn, indices); err != nil {
+		t.Fatal(err)
+	}
+
+	sigHash := cs.InputSigHash(txn)
+	for i, sci := range txn.SiacoinInputs {
+		pk := wallet.KeyFromSeed(seed, indices[i]).PublicKey()
+		if len(sci.SatisfiedPolicy.Signatures) != 1 || !pk.VerifyHash(sigHash, sci.SatisfiedPolicy.Signatures[0]) {
+			t.Fatalf("invalid siacoin input signature %d", i)
+		}
+	}
+	if sigs := txn.SiafundInputs[0].SatisfiedPolicy.Signatures; len(sigs) != 1 || !pk.VerifyHash(sigHash, sigs[0]) {
+		t.Fatal("invalid siafund input signature")
+	}
+
+	if err := SignV2Transaction(cs, seed, &txn, indices[:2]); err == nil {
+		t.Fatal("expected index count error")
+	}
+}
+
+func TestSignV2TransactionPolicies(t *testing.T) {
+	seed := testSeed(t)
+	v1, v2 := GenerateAddress(seed, 0), GeneratePolicyAddress(seed, 1)
+
+	cs, sces := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: v1.Address, Value: types.Siacoins(10)},
+		types.SiacoinOutput{Address: v2.Address, Value: types.Siacoins(10)},
+	)
+	var txn types.V2Transaction
+	for _, sce := range sces {
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{Parent: sce})
+	}
+	txn.SiacoinOutputs = []types.SiacoinOutput{{Address: frand.Entropy256(), Value: types.Siacoins(19)}}
+	txn.MinerFee = types.Siacoins(1)
+	indices := make([]uint64, len(sces))
+	for i, sce := range sces {
+		if sce.SiacoinOutput.Address == v2.Address {
+			indices[i] = 1
+		}
+	}
+
+	// a wrong policy is replaced, and no input is signed on a mismatch
+	txn.SiacoinInputs[0].SatisfiedPolicy.Policy = types.PolicyPublicKey(types.PublicKey{1})
+	wrong := txn.DeepCopy()
+	wrong.SiacoinInputs[1].Parent.SiacoinOutput.Address = GenerateAddress(seed, 2).Address
+	if err := SignV2Transaction(cs, seed, &wrong, indices); err == nil || !strings.Contains(err.Error(), "siacoin input 1") {
+		t.Fatalf("expected mismatch error naming siacoin input 1, got %v", err)
+	} else if len(wrong.SiacoinInputs[0].SatisfiedPolicy.Signatures) != 0 {
+		t.Fatal("expected no inputs to be signed")
+	}
+
+	if err := SignV2Transaction(cs, seed, &txn, indices); err != nil {
+		t.Fatal(err)
+	}
+	for i, sci := range txn.SiacoinInputs {
+		if sci.SatisfiedPolicy.Policy.Address() != sci.Parent.SiacoinOutput.Address {
+			t.Fatalf("policy of input %d does not match its parent", i)
+		}
+	}
+	if res := ValidateV2Transaction(cs, txn, sces, nil, types.ZeroCurrency); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"fmt"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+// A sweepInput is an output spent by sweepOutputs and the key that signs for
+// it.
+type sweepInput struct {
+	Parent types.SiacoinElement
+	Policy types.SpendPolicy
+	Key    types.PrivateKey
+}
+
+// sweepOutputs returns a signed transaction sending the value of the inputs,
+// less the miner fee, to the destination address. Each input's policy is
+// satisfied by its key's signature and the preimages.
+func sweepOutputs(cs consensus.State, inputs []sweepInput, preimages map[types.Hash256][32]byte, destination types.Address, feeRate types.Currency) (types.V2Transaction, error) {
+	satisfy := func(txn *types.V2Transaction, sign func(types.PrivateKey) types.Signature) error {
+		for i, in := range inputs {
+			sigs := map[types.PublicKey]types.Signature{in.Key.PublicKey(): sign(in.Key)}
+			sp, err := satisfyPolicy(in.Policy, sigs, preimages)
+			if err != nil {
+				return fmt.Errorf("siacoin input %d: %w", i, err)
+			}
+			txn.SiacoinInputs[i].SatisfiedPolicy = sp
+		}
+		return nil
+	}
+
+	txn := types.V2Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Address: destination}},
+	}
+	var inputSum types.Currency
+	for _, in := range inputs {
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.V2SiacoinInput{Parent: in.Parent.Copy()})
+		inputSum = inputSum.Add(in.Parent.SiacoinOutput.Value)
+	}
+
+	// satisfy the policies with placeholder signatures to weigh the
+	// transaction
+	if err := satisfy(&txn, func(types.PrivateKey) types.Signature { return types.Signature{} }); err != nil {
+		return types.V2Transaction{}, err
+	}
+	txn.MinerFee = feeRate.Mul64(cs.V2TransactionWeight(txn))
+	if inputSum.Cmp(txn.MinerFee) <= 0 {
+		return types.V2Transaction{}, fmt.Errorf("%w: inputs (%v) do not cover the fee (%v)", ErrInsufficientFunds, inputSum, txn.MinerFee)
+	}
+	txn.SiacoinOutputs[0].Value = inputSum.Sub(txn.MinerFee)
+
+	sigHash := cs.InputSigHash(txn)
+	if err := satisfy(&txn, func(sk types.PrivateKey) types.Signature { return sk.SignHash(sigHash) }); err != nil {
+		return types.V2Transaction{}, err
+	}
+	return txn, nil
+}
@@ -0,0 +1,244 @@
+package wallet
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"go.sia.tech/coreutils/chain"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// A ProcessedTransaction summarizes the effect of a wallet event on a set of
+// wallet addresses.
+type ProcessedTransaction struct {
+	ID             types.Hash256  `json:"id"`
+	BlockHeight    uint64         `json:"block_height"`
+	Confirmations  uint64         `json:"confirmations"`
+	Timestamp      time.Time      `json:"timestamp"`
+	Fees           types.Currency `json:"fees"`
+	SiacoinInputs  types.Currency `json:"siacoin_inputs"`
+	SiacoinOutputs types.Currency `json:"siacoin_outputs"`
+	SiafundInputs  uint64         `json:"siafund_inputs"`
+	SiafundOutputs uint64         `json:"siafund_outputs"`
+	Tags           []string       `json:"tags,omitempty"`
+}
+
+func hasAnnouncement(txn types.Transaction) bool {
+	for _, arb := range txn.ArbitraryData {
+		var ha chain.HostAnnouncement
+		if ha.FromArbitraryData(arb) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasV2Announcement(txn types.V2Transaction) bool {
+	for _, attestation := range txn.Attestations {
+		var ha chain.V2HostAnnouncement
+		if err := ha.FromAttestation(attestation); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WalletTransactions returns the unconfirmed transactions followed by the 100
+// most recent confirmed transactions relevant to the addresses.
+func WalletTransactions(w backend.Backend, addresses []types.Address) ([]ProcessedTransaction, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	relevantAddresses := make(map[types.Address]bool, len(addresses))
+	for _, addr := range addresses {
+		relevantAddresses[addr] = true
+	}
+
+	processEvent := func(event wallet.Event) (ProcessedTransaction, bool) {
+		processed := ProcessedTransaction{
+			ID:            event.ID,
+			Timestamp:     event.Timestamp,
+			BlockHeight:   event.Index.Height,
+			Confirmations: event.Confirmations,
+		}
+		var relevant bool
+		switch data := event.Data.(type) {
+		case wallet.EventV1Transaction:
+			switch {
+			case len(data.Transaction.FileContracts) > 0 && len(data.Transaction.FileContractRevisions) > 0:
+				processed.Tags = append(processed.Tags, "contract_renewal")
+			case len(data.Transaction.FileContractRevisions) > 0:
+				processed.Tags = append(processed.Tags, "contract_revision")
+			case len(data.Transaction.FileContracts) > 0:
+				processed.Tags = append(processed.Tags, "contract_formation")
+			case len(data.Transaction.StorageProofs) > 0:
+				processed.Tags = append(processed.Tags, "storage_proof")
+			case hasAnnouncement(data.Transaction):
+				processed.Tags = append(processed.Tags, "host_announcement")
+			case max(len(data.Transaction.SiafundInputs), len(data.Transaction.SiafundOutputs)) > 0:
+				processed.Tags = append(processed.Tags, "siafund_transaction")
+			case max(len(data.Transaction.SiacoinInputs), len(data.Transaction.SiacoinOutputs)) > 0:
+				processed.Tags = append(processed.Tags, "siacoin_transaction")
+			}
+			for _, sce := range data.SpentSiacoinElements {
+				if !relevantAddresses[sce.SiacoinOutput.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiacoinInputs = processed.SiacoinInputs.Add(sce.SiacoinOutput.Value)
+			}
+			for _, sco := range data.Transaction.SiacoinOutputs {
+				if !relevantAddresses[sco.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiacoinOutputs = processed.SiacoinOutputs.Add(sco.Value)
+			}
+			for _, sfe := range data.SpentSiafundElements {
+				if !relevantAddresses[sfe.SiafundOutput.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiafundInputs += sfe.SiafundOutput.Value
+			}
+			for _, sfo := range data.Transaction.SiafundOutputs {
+				if !relevantAddresses[sfo.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiafundOutputs += sfo.Value
+			}
+		case wallet.EventV2Transaction:
+			switch {
+			case len(data.FileContractRevisions) > 0:
+				processed.Tags = append(processed.Tags, "contract_revision")
+			case len(data.FileContracts) > 0:
+				processed.Tags = append(processed.Tags, "contract_formation")
+			case len(data.FileContractResolutions) > 0:
+				fce := data.FileContractResolutions[0].Parent
+				switch res := data.FileContractResolutions[0].Resolution.(type) {
+				case *types.V2StorageProof:
+					processed.Tags = append(processed.Tags, "storage_proof")
+				case *types.V2FileContractRenewal:
+					if res.NewContract.ProofHeight == fce.V2FileContract.ProofHeight && res.NewContract.ExpirationHeight == fce.V2FileContract.ExpirationHeight {
+						processed.Tags = append(processed.Tags, "contract_refresh")
+					} else {
+						processed.Tags = append(processed.Tags, "contract_renewal")
+					}
+				case *types.V2FileContractExpiration:
+					processed.Tags = append(processed.Tags, "contract_expiration")
+				default:
+					processed.Tags = append(processed.Tags, "contract_resolution")
+				}
+			case hasV2Announcement(types.V2Transaction(data)):
+				processed.Tags = append(processed.Tags, "host_announcement")
+			case max(len(data.SiafundInputs), len(data.SiafundOutputs)) > 0:
+				processed.Tags = append(processed.Tags, "siafund_transaction")
+			case max(len(data.SiacoinInputs), len(data.SiacoinOutputs)) > 0:
+				processed.Tags = append(processed.Tags, "siacoin_transaction")
+			}
+			for _, sci := range data.SiacoinInputs {
+				if !relevantAddresses[sci.Parent.SiacoinOutput.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiacoinInputs = processed.SiacoinInputs.Add(sci.Parent.SiacoinOutput.Value)
+			}
+			for _, sco := range data.SiacoinOutputs {
+				if !relevantAddresses[sco.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiacoinOutputs = processed.SiacoinOutputs.Add(sco.Value)
+			}
+			for _, sfi := range data.SiafundInputs {
+				if !relevantAddresses[sfi.Parent.SiafundOutput.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiafundInputs += sfi.Parent.SiafundOutput.Value
+			}
+			for _, sfo := range data.SiafundOutputs {
+				if !relevantAddresses[sfo.Address] {
+					continue
+				}
+				relevant = true
+				processed.SiafundOutputs += sfo.Value
+			}
+		case wallet.EventV1ContractResolution:
+			relevant = true
+			if data.Missed {
+				processed.Tags = append(processed.Tags, "contract_missed_output")
+			} else {
+				processed.Tags = append(processed.Tags, "contract_valid_output")
+			}
+			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
+		case wallet.EventV2ContractResolution:
+			if data.SiacoinElement.SiacoinOutput.Value.IsZero() {
+				break
+			}
+			relevant = true
+			if data.Missed {
+				processed.Tags = append(processed.Tags, "contract_missed_output")
+			} else {
+				processed.Tags = append(processed.Tags, "contract_valid_output")
+			}
+			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
+		case wallet.EventPayout:
+			relevant = true
+			processed.Tags = append(processed.Tags, "payout")
+			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
+		}
+		return processed, relevant
+	}
+
+	events, err := w.TPoolEvents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unconfirmed transactions: %w", err)
+	}
+
+	var unconfirmed []ProcessedTransaction
+	for _, event := range events {
+		processed, relevant := processEvent(event)
+		if relevant {
+			unconfirmed = append(unconfirmed, processed)
+		}
+	}
+
+	var transactions []ProcessedTransaction
+	seen := make(map[types.Hash256]bool)
+	batch := min(100, len(addresses))
+	for i := 0; i < len(addresses); i += batch {
+		addressBatch := addresses[i:min(i+batch, len(addresses))]
+		events, err := w.BatchAddressEvents(addressBatch, 0, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get wallet events: %w", err)
+		}
+		log.Println("events for addresses", len(addressBatch), ":", len(events))
+
+		for _, event := range events {
+			if seen[event.ID] {
+				continue // skip already processed events
+			}
+			seen[event.ID] = true
+			processed, relevant := processEvent(event)
+			if !relevant {
+				continue // should never happen, but just in case
+			}
+			transactions = append(transactions, processed)
+			sort.SliceStable(transactions, func(i, j int) bool {
+				// sort by timestamp, newest first
+				return transactions[i].Timestamp.After(transactions[j].Timestamp)
+			})
+			if len(transactions) >= 100 {
+				transactions = transactions[:100]
+			}
+		}
+	}
+	return append(unconfirmed, transactions...), nil
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+	"lukechampine.com/frand"
+)
+
+func TestWalletSummary(t *testing.T) {
+	seed := testSeed(t)
+	cs := testState(1000)
+	cs.SiafundTaxRevenue = types.Siacoins(10000)
+	b := backend.NewMemory(cs)
+
+	addr0 := GenerateAddress(seed, 0).Address
+	addr1 := GenerateAddress(seed, 1).Address
+	other := types.Address(frand.Entropy256())
+	timestamp := time.Now().Add(-time.Hour)
+
+	// v1 send from addr0 to another wallet with change to addr1
+	send := wallet.Event{
+		ID:        frand.Entropy256(),
+		Index:     types.ChainIndex{Height: 10},
+		Timestamp: timestamp,
+		Type:      wallet.EventTypeV1Transaction,
+		Data: wallet.EventV1Transaction{
+			Transaction: types.Transaction{
+				SiacoinInputs: []types.SiacoinInput{{ParentID: frand.Entropy256()}},
+				SiacoinOutputs: []types.SiacoinOutput{
+					{Address: other, Value: types.Siacoins(7)},
+					{Address: addr1, Value: types.Siacoins(3)},
+				},
+			},
+			SpentSiacoinElements: []types.SiacoinElement{
+				{SiacoinOutput: types.SiacoinOutput{Address: addr0, Value: types.Siacoins(10)}},
+			},
+		},
+		Relevant: []types.Address{addr0, addr1},
+	}
+	// v2 contract formation funded by addr1
+	formation := wallet.Event{
+		ID:        frand.Entropy256(),
+		Index:     types.ChainIndex{Height: 20},
+		Timestamp: timestamp.Add(time.Minute),
+		Type:      wallet.EventTypeV2Transaction,
+		Data: wallet.EventV2Transaction{
+			SiacoinInputs: []types.V2SiacoinInput{{
+				Parent: types.SiacoinElement{SiacoinOutput: types.SiacoinOutput{Address: addr1, Value: types.Siacoins(3)}},
+			}},
+			FileContracts: []types.V2FileContract{{}},
+		},
+		Relevant: []types.Address{addr1},
+	}
+	// miner payout to addr0
+	payout := wallet.Event{
+		ID:        frand.Entropy256(),
+		Index:     types.ChainIndex{Height: 30},
+		Timestamp: timestamp.Add(2 * time.Minute),
+		Type:      wallet.EventTypeMinerPayout,
+		Data: wallet.EventPayout{
+			SiacoinElement: types.SiacoinElement{SiacoinOutput: types.SiacoinOutput{Address: addr0, Value: types.Siacoins(300000)}},
+		},
+		Relevant: []types.Address{addr0},
+	}
+	// unconfirmed v2 siafund transaction to addr0
+	unconfirmed := wallet.Event{
+		ID:   frand.Entropy256(),
+		Type: wallet.EventTypeV2Transaction,
+		Data: wallet.EventV2Transaction{
+			SiafundOutputs: []types.SiafundOutput{{Address: addr0, Value: 5}},
+		},
+		Relevant: []types.Address{addr0},
+	}
+	b.AddEvents(send, formation, payout)
+	b.AddUnconfirmedEvents(unconfirmed)
+
+	b.AddSiacoinElements(types.SiacoinElement{
+		ID:             frand.Entropy256(),
+		SiacoinOutput:  types.SiacoinOutput{Address: addr0, Value: types.Siacoins(300000)},
+		MaturityHeight: 180,
+	}, types.SiacoinElement{
+		ID:             frand.Entropy256(),
+		SiacoinOutput:  types.SiacoinOutput{Address: addr1, Value: types.Siacoins(1)},
+		MaturityHeight: 2000, // immature
+	})
+	b.AddSiafundElements(types.SiafundElement{
+		ID:            frand.Entropy256(),
+		SiafundOutput: types.SiafundOutput{Address: addr0, Value: 10},
+	})
+
+	summary, err := WalletSummary(b, []types.Address{addr0, addr1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !summary.ConfirmedSiacoinBalance.Equals(types.Siacoins(300000)) {
+		t.Fatalf("expected 300000 SC, got %v", summary.ConfirmedSiacoinBalance)
+	} else if summary.ConfirmedSiafundBalance != 10 {
+		t.Fatalf("expected 10 SF, got %v", summary.ConfirmedSiafundBalance)
+	} else if len(summary.UnspentSiacoinOutputs) != 1 {
+		t.Fatalf("expected 1 mature siacoin output, got %d", len(summary.UnspentSiacoinOutputs))
+	} else if len(summary.UnspentSiafundOutputs) != 1 {
+		t.Fatalf("expected 1 siafund output, got %d", len(summary.UnspentSiafundOutputs))
+	} else if !summary.SiafundClaim.Equals(types.Siacoins(10)) {
+		t.Fatalf("expected 10 SC siafund claim, got %v", summary.SiafundClaim)
+	}
+
+	expected := []struct {
+		id             types.Hash256
+		tag            string
+		siacoinInputs  types.Currency
+		siacoinOutputs types.Currency
+		siafundOutputs uint64
+	}{
+		{unconfirmed.ID, "siafund_transaction", types.ZeroCurrency, types.ZeroCurrency, 5},
+		{payout.ID, "payout", types.ZeroCurrency, types.Siacoins(300000), 0},
+		{formation.ID, "contract_formation", types.Siacoins(3), types.ZeroCurrency, 0},
+		{send.ID, "siacoin_transaction", types.Siacoins(10), types.Siacoins(3), 0},
+	}
+	if len(summary.Transactions) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(summary.Transactions))
+	}
+	for i, txn := range summary.Transactions {
+		exp := expected[i]
+		switch {
+		case txn.ID != exp.id:
+			t.Fatalf("transaction %d: expected ID %v, got %v", i, exp.id, txn.ID)
+		case !slices.Equal(txn.Tags, []string{exp.tag}):
+			t.Fatalf("transaction %d: expected tag %q, got %v", i, exp.tag, txn.Tags)
+		case !txn.SiacoinInputs.Equals(exp.siacoinInputs):
+			t.Fatalf("transaction %d: expected %v siacoin inputs, got %v", i, exp.siacoinInputs, txn.SiacoinInputs)
+		case !txn.SiacoinOutputs.Equals(exp.siacoinOutputs):
+			t.Fatalf("transaction %d: expected %v siacoin outputs, got %v", i, exp.siacoinOutputs, txn.SiacoinOutputs)
+		case txn.SiafundOutputs != exp.siafundOutputs:
+			t.Fatalf("transaction %d: expected %v siafund outputs, got %v", i, exp.siafundOutputs, txn.SiafundOutputs)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+)
+
+// maxCheckAddresses is the maximum number of addresses sent in a single
+// CheckAddresses request.
+const maxCheckAddresses = 1000
+
+// checkAddresses returns true if any of the addresses have been seen on
+// chain. The addresses are split into requests of at most maxCheckAddresses.
+func checkAddresses(b backend.Backend, addresses []types.Address) (bool, error) {
+	for i := 0; i < len(addresses); i += maxCheckAddresses {
+		used, err := b.CheckAddresses(addresses[i:min(i+maxCheckAddresses, len(addresses))])
+		if err != nil {
+			return false, fmt.Errorf("error checking addresses: %w", err)
+		} else if used {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findUsedGroups returns whether each group of addresses contains an address
+// that has been seen on chain. Groups are checked together and a batch is
+// only split in half when one of its groups is used, so unused groups cost
+// no extra requests.
+func findUsedGroups(ctx context.Context, b backend.Backend, groups [][]types.Address) ([]bool, error) {
+	used := make([]bool, len(groups))
+
+	var check func(start, end int) error
+	check = func(start, end int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch []types.Address
+		for _, g := range groups[start:end] {
+			batch = append(batch, g...)
+		}
+		ok, err := checkAddresses(b, batch)
+		if err != nil {
+			return err
+		} else if !ok {
+			return nil
+		} else if end-start == 1 {
+			used[start] = true
+			return nil
+		}
+		mid := (start + end) / 2
+		if err := check(start, mid); err != nil {
+			return err
+		}
+		return check(mid, end)
+	}
+
+	// start with batches of whole groups that fit in a single request
+	for start := 0; start < len(groups); {
+		end, n := start+1, len(groups[start])
+		for end < len(groups) && n+len(groups[end]) <= maxCheckAddresses {
+			n += len(groups[end])
+			end++
+		}
+		if err := check(start, end); err != nil {
+			return nil, err
+		}
+		start = end
+	}
+	return used, nil
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+// countingBackend counts CheckAddresses requests and their size.
+type countingBackend struct {
+	*backend.Memory
+	requests int
+	largest  int
+}
+
+func (cb *countingBackend) CheckAddresses(addresses []types.Address) (bool, error) {
+	cb.requests++
+	cb.largest = max(cb.largest, len(addresses))
+	return cb.Memory.CheckAddresses(addresses)
+}
+
+func TestFindUsedGroups(t *testing.T) {
+	b := &countingBackend{Memory: backend.NewMemory(testState(1000))}
+
+	groups := make([][]types.Address, 1000)
+	for i := range groups {
+		groups[i] = []types.Address{frand.Entropy256(), frand.Entropy256()}
+	}
+	// a single group larger than a request
+	for range 2500 {
+		groups[999] = append(groups[999], frand.Entropy256())
+	}
+	for _, i := range []int{17, 999} {
+		b.AddSiacoinElements(types.SiacoinElement{
+			ID:            frand.Entropy256(),
+			SiacoinOutput: types.SiacoinOutput{Address: groups[i][len(groups[i])-1], Value: types.Siacoins(1)},
+		})
+	}
+
+	used, err := findUsedGroups(context.Background(), b, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range used {
+		if u != (i == 17 || i == 999) {
+			t.Fatalf("group %d: expected used %v, got %v", i, !u, u)
+		}
+	}
+	if b.largest > maxCheckAddresses {
+		t.Fatalf("expected at most %d addresses per request, got %d", maxCheckAddresses, b.largest)
+	} else if b.requests > 50 {
+		t.Fatalf("expected bisection to need few requests, got %d", b.requests)
+	}
+}
@@ -0,0 +1,304 @@
+package wallet
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+)
+
+// A ValidationCode identifies the reason a transaction is invalid.
+type ValidationCode string
+
+// Reasons a v2 transaction can fail validation.
+const (
+	CodeHardfork       ValidationCode = "hardfork"
+	CodeEmpty          ValidationCode = "empty"
+	CodeWeight         ValidationCode = "weight"
+	CodeDoubleSpend    ValidationCode = "double_spend"
+	CodeMissingParent  ValidationCode = "missing_parent"
+	CodeParentMismatch ValidationCode = "parent_mismatch"
+	CodeImmature       ValidationCode = "immature"
+	CodePolicy         ValidationCode = "policy"
+	CodeSignature      ValidationCode = "signature"
+	CodeZeroOutput     ValidationCode = "zero_output"
+	CodeBalance        ValidationCode = "balance"
+	CodeOverflow       ValidationCode = "overflow"
+	CodeFee            ValidationCode = "fee"
+	// CodeConsensus is a failure reported by the consensus validation that
+	// is not covered by another code.
+	CodeConsensus ValidationCode = "consensus"
+)
+
+type (
+	// A ValidationIssue is a reason a transaction is invalid.
+	ValidationIssue struct {
+		Code ValidationCode `json:"code"`
+		// SiacoinInput or SiafundInput is the index of the input the issue
+		// applies to, if any.
+		SiacoinInput *int   `json:"siacoin_input,omitempty"`
+		SiafundInput *int   `json:"siafund_input,omitempty"`
+		Message      string `json:"message"`
+	}
+
+	// A ValidationResult is the result of validating a v2 transaction
+	// against a consensus state.
+	ValidationResult struct {
+		Valid  bool                `json:"valid"`
+		ID     types.TransactionID `json:"id"`
+		Weight uint64              `json:"weight"`
+		Fee    types.Currency      `json:"fee"`
+		// MinFee is the minimum fee at the requested fee rate.
+		MinFee types.Currency    `json:"min_fee"`
+		Issues []ValidationIssue `json:"issues"`
+	}
+)
+
+// medianTimestamp returns the median timestamp of the state's previous
+// blocks, used to evaluate time locked spend policies.
+func medianTimestamp(cs consensus.State) time.Time {
+	n := len(cs.PrevTimestamps)
+	if childHeight := cs.Index.Height + 1; childHeight < uint64(n) {
+		n = int(childHeight)
+	}
+	prev := cs.PrevTimestamps
+	ts := prev[:n]
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+	if len(ts)%2 != 0 {
+		return ts[len(ts)/2]
+	}
+	l, r := ts[len(ts)/2-1], ts[len(ts)/2]
+	return l.Add(r.Sub(l) / 2)
+}
+
+// FetchV2Parents returns the current unspent elements spent by the
+// transaction's inputs and the chain index their proofs are valid at. Inputs
+// whose parent is spent or unconfirmed are not included.
+func FetchV2Parents(w backend.Backend, txn types.V2Transaction) (types.ChainIndex, []types.SiacoinElement, []types.SiafundElement, error) {
+	parents := make(map[types.Hash256]bool)
+	seen := make(map[types.Address]bool)
+	var addresses []types.Address
+	addAddress := func(addr types.Address) {
+		if !seen[addr] {
+			seen[addr] = true
+			addresses = append(addresses, addr)
+		}
+	}
+	for _, sci := range txn.SiacoinInputs {
+		parents[types.Hash256(sci.Parent.ID)] = true
+		addAddress(sci.Parent.SiacoinOutput.Address)
+	}
+	for _, sfi := range txn.SiafundInputs {
+		parents[types.Hash256(sfi.Parent.ID)] = true
+		addAddress(sfi.Parent.SiafundOutput.Address)
+	}
+
+	basis, unspentSiacoins, unspentSiafunds, err := unspentElements(w, addresses, len(txn.SiafundInputs) != 0)
+	if err != nil {
+		return types.ChainIndex{}, nil, nil, err
+	}
+	var sces []types.SiacoinElement
+	for _, sce := range unspentSiacoins {
+		if parents[types.Hash256(sce.ID)] {
+			sces = append(sces, sce)
+		}
+	}
+	var sfes []types.SiafundElement
+	for _, sfe := range unspentSiafunds {
+		if parents[types.Hash256(sfe.ID)] {
+			sfes = append(sfes, sfe)
+		}
+	}
+	return basis, sces, sfes, nil
+}
+
+// ValidateV2Transaction checks that txn is valid in the block after cs. The
+// transaction's parent elements are replaced by the matching current elements
+// in sces and sfes, so the parents' proofs do not need to be up to date. If
+// minFeeRate is not zero, the miner fee must cover the transaction's weight
+// at that rate.
+//
+// Every problem that can be attributed to an input or output is reported.
+// Any remaining failure from the consensus validation is reported as
+// CodeConsensus.
+func ValidateV2Transaction(cs consensus.State, txn types.V2Transaction, sces []types.SiacoinElement, sfes []types.SiafundElement, minFeeRate types.Currency) ValidationResult {
+	res := ValidationResult{
+		ID:     txn.ID(),
+		Weight: cs.V2TransactionWeight(txn),
+		Fee:    txn.MinerFee,
+		Issues: []ValidationIssue{},
+	}
+	res.MinFee = minFeeRate.Mul64(res.Weight)
+
+	addIssue := func(code ValidationCode, format string, args ...any) {
+		res.Issues = append(res.Issues, ValidationIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	addSiacoinIssue := func(i int, code ValidationCode, format string, args ...any) {
+		res.Issues = append(res.Issues, ValidationIssue{Code: code, SiacoinInput: &i, Message: fmt.Sprintf(format, args...)})
+	}
+	addSiafundIssue := func(i int, code ValidationCode, format string, args ...any) {
+		res.Issues = append(res.Issues, ValidationIssue{Code: code, SiafundInput: &i, Message: fmt.Sprintf(format, args...)})
+	}
+
+	childHeight := cs.Index.Height + 1
+	if childHeight < cs.Network.HardforkV2.AllowHeight {
+		addIssue(CodeHardfork, "v2 transactions are not allowed until height %d", cs.Network.HardforkV2.AllowHeight)
+	}
+	if res.Weight == 0 {
+		addIssue(CodeEmpty, "transaction is empty")
+	} else if res.Weight > cs.MaxBlockWeight() {
+		addIssue(CodeWeight, "transaction weight %d exceeds the maximum block weight %d", res.Weight, cs.MaxBlockWeight())
+	}
+
+	currentSiacoins := make(map[types.SiacoinOutputID]types.SiacoinElement, len(sces))
+	for _, sce := range sces {
+		currentSiacoins[sce.ID] = sce
+	}
+	currentSiafunds := make(map[types.SiafundOutputID]types.SiafundElement, len(sfes))
+	for _, sfe := range sfes {
+		currentSiafunds[sfe.ID] = sfe
+	}
+
+	// copy the inputs so the caller's transaction is not modified
+	txn.SiacoinInputs = append([]types.V2SiacoinInput(nil), txn.SiacoinInputs...)
+	txn.SiafundInputs = append([]types.V2SiafundInput(nil), txn.SiafundInputs...)
+
+	sigHash := cs.InputSigHash(txn)
+	median := medianTimestamp(cs)
+	checkPolicy := func(sp types.SatisfiedPolicy, parentAddress types.Address) (ValidationCode, error) {
+		if sp.Policy.Address() != parentAddress {
+			return CodePolicy, fmt.Errorf("spend policy address %v does not match parent address %v", sp.Policy.Address(), parentAddress)
+		} else if err := sp.Policy.Verify(cs.Index.Height, median, sigHash, sp.Signatures, sp.Preimages); err != nil {
+			return CodeSignature, fmt.Errorf("failed to satisfy spend policy: %w", err)
+		}
+		return "", nil
+	}
+
+	spentSiacoins := make(map[types.SiacoinOutputID]int)
+	for i, sci := range txn.SiacoinInputs {
+		if j, ok := spentSiacoins[sci.Parent.ID]; ok {
+			addSiacoinIssue(i, CodeDoubleSpend, "parent %v is already spent by input %d", sci.Parent.ID, j)
+			continue
+		}
+		spentSiacoins[sci.Parent.ID] = i
+
+		// ephemeral parents are created by another unconfirmed transaction
+		// and are checked by the consensus validation
+		if sci.Parent.StateElement.LeafIndex != types.UnassignedLeafIndex {
+			current, ok := currentSiacoins[sci.Parent.ID]
+			if !ok {
+				addSiacoinIssue(i, CodeMissingParent, "parent %v is spent or does not exist", sci.Parent.ID)
+				continue
+			} else if current.SiacoinOutput != sci.Parent.SiacoinOutput || current.MaturityHeight != sci.Parent.MaturityHeight {
+				addSiacoinIssue(i, CodeParentMismatch, "parent %v does not match the output on chain", sci.Parent.ID)
+			}
+			txn.SiacoinInputs[i].Parent = current.Copy()
+			sci.Parent = current
+		}
+
+		if sci.Parent.MaturityHeight > childHeight {
+			addSiacoinIssue(i, CodeImmature, "parent %v is not spendable until height %d", sci.Parent.ID, sci.Parent.MaturityHeight)
+		}
+		if code, err := checkPolicy(sci.SatisfiedPolicy, sci.Parent.SiacoinOutput.Address); err != nil {
+			addSiacoinIssue(i, code, "%s", err)
+		}
+	}
+
+	spentSiafunds := make(map[types.SiafundOutputID]int)
+	for i, sfi := range txn.SiafundInputs {
+		if j, ok := spentSiafunds[sfi.Parent.ID]; ok {
+			addSiafundIssue(i, CodeDoubleSpend, "parent %v is already spent by input %d", sfi.Parent.ID, j)
+			continue
+		}
+		spentSiafunds[sfi.Parent.ID] = i
+
+		if sfi.Parent.StateElement.LeafIndex != types.UnassignedLeafIndex {
+			current, ok := currentSiafunds[sfi.Parent.ID]
+			if !ok {
+				addSiafundIssue(i, CodeMissingParent, "parent %v is spent or does not exist", sfi.Parent.ID)
+				continue
+			} else if current.SiafundOutput != sfi.Parent.SiafundOutput || current.ClaimStart != sfi.Parent.ClaimStart {
+				addSiafundIssue(i, CodeParentMismatch, "parent %v does not match the output on chain", sfi.Parent.ID)
+			}
+			txn.SiafundInputs[i].Parent = current.Copy()
+			sfi.Parent = current
+		}
+
+		if code, err := checkPolicy(sfi.SatisfiedPolicy, sfi.Parent.SiafundOutput.Address); err != nil {
+			addSiafundIssue(i, code, "%s", err)
+		}
+	}
+
+	for i, sco := range txn.SiacoinOutputs {
+		if sco.Value.IsZero() {
+			addIssue(CodeZeroOutput, "siacoin output %d has zero value", i)
+		}
+	}
+	for i, sfo := range txn.SiafundOutputs {
+		if sfo.Value == 0 {
+			addIssue(CodeZeroOutput, "siafund output %d has zero value", i)
+		}
+	}
+
+	// contracts move value in ways that are left to the consensus validation
+	if len(txn.FileContracts) == 0 && len(txn.FileContractResolutions) == 0 {
+		var inputSum, outputSum types.Currency
+		var inOverflow, outOverflow bool
+		for _, sci := range txn.SiacoinInputs {
+			var o bool
+			inputSum, o = inputSum.AddWithOverflow(sci.Parent.SiacoinOutput.Value)
+			inOverflow = inOverflow || o
+		}
+		for _, sco := range txn.SiacoinOutputs {
+			var o bool
+			outputSum, o = outputSum.AddWithOverflow(sco.Value)
+			outOverflow = outOverflow || o
+		}
+		var o bool
+		outputSum, o = outputSum.AddWithOverflow(txn.MinerFee)
+		outOverflow = outOverflow || o
+
+		switch {
+		case inOverflow:
+			addIssue(CodeOverflow, "siacoin inputs overflow")
+		case outOverflow:
+			addIssue(CodeOverflow, "siacoin outputs plus the miner fee overflow")
+		case inputSum != outputSum:
+			addIssue(CodeBalance, "siacoin inputs (%v) do not equal outputs plus the miner fee (%v)", inputSum, outputSum)
+		}
+	}
+	var sfIn, sfOut, carry uint64
+	var sfInOverflow, sfOutOverflow bool
+	for _, sfi := range txn.SiafundInputs {
+		sfIn, carry = bits.Add64(sfIn, sfi.Parent.SiafundOutput.Value, 0)
+		sfInOverflow = sfInOverflow || carry != 0
+	}
+	for _, sfo := range txn.SiafundOutputs {
+		sfOut, carry = bits.Add64(sfOut, sfo.Value, 0)
+		sfOutOverflow = sfOutOverflow || carry != 0
+	}
+	switch {
+	case sfInOverflow:
+		addIssue(CodeOverflow, "siafund inputs overflow")
+	case sfOutOverflow:
+		addIssue(CodeOverflow, "siafund outputs overflow")
+	case sfIn != sfOut:
+		addIssue(CodeBalance, "siafund inputs (%d SF) do not equal outputs (%d SF)", sfIn, sfOut)
+	}
+
+	if txn.MinerFee.Cmp(res.MinFee) < 0 {
+		addIssue(CodeFee, "miner fee %v is less than the minimum fee %v", txn.MinerFee, res.MinFee)
+	}
+
+	if len(res.Issues) == 0 {
+		if err := consensus.ValidateV2Transaction(consensus.NewMidState(cs), txn); err != nil {
+			addIssue(CodeConsensus, "%s", err)
+		}
+	}
+	res.Valid = len(res.Issues) == 0
+	return res
+}
@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+// testChainElements applies a block creating outputs to the state and returns
+// the child state and the created elements with valid proofs.
+func testChainElements(cs consensus.State, outputs ...types.SiacoinOutput) (consensus.State, []types.SiacoinElement) {
+	b := types.Block{
+		ParentID:  cs.Index.ID,
+		Timestamp: time.Now(),
+		V2: &types.V2BlockData{
+			Height:       cs.Index.Height + 1,
+			Transactions: []types.V2Transaction{{SiacoinOutputs: outputs}},
+		},
+	}
+	cs, au := consensus.ApplyBlock(cs, b, consensus.V1BlockSupplement{}, time.Time{})
+
+	created := make(map[types.Address]bool)
+	for _, sco := range outputs {
+		created[sco.Address] = true
+	}
+	var sces []types.SiacoinElement
+	for _, diff := range au.SiacoinElementDiffs() {
+		if diff.Created && created[diff.SiacoinElement.SiacoinOutput.Address] {
+			sces = append(sces, diff.SiacoinElement.Copy())
+		}
+	}
+	return cs, sces
+}
+
+func hasIssue(res ValidationResult, code ValidationCode) bool {
+	for _, issue := range res.Issues {
+		if issue.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateV2Transaction(t *testing.T) {
+	seed := testSeed(t)
+	addresses := GenerateAddresses(seed, 0, 2)
+	feeRate := types.Siacoins(1).Div64(1000)
+
+	cs, sces := testChainElements(testState(600000),
+		types.SiacoinOutput{Address: addresses[0].Address, Value: types.Siacoins(10)},
+		types.SiacoinOutput{Address: addresses[1].Address, Value: types.Siacoins(20)},
+	)
+	b := backend.NewMemory(cs)
+	b.AddSiacoinElements(sces...)
+
+	resp, err := BuildV2Transaction(cs, sces, BuildRequest{
+		Addresses:  addresses,
+		Recipients: []Recipient{{Address: frand.Entropy256(), Value: types.Siacoins(25)}},
+		FeeRate:    feeRate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := resp.Transaction.DeepCopy()
+	if err := SignV2Transaction(cs, seed, &txn, resp.SigningIndices); err != nil {
+		t.Fatal(err)
+	}
+
+	validate := func(txn types.V2Transaction, feeRate types.Currency) ValidationResult {
+		t.Helper()
+		basis, sces, sfes, err := FetchV2Parents(b, txn)
+		if err != nil {
+			t.Fatal(err)
+		} else if basis != cs.Index {
+			t.Fatalf("expected basis %v, got %v", cs.Index, basis)
+		}
+		return ValidateV2Transaction(cs, txn, sces, sfes, feeRate)
+	}
+
+	if res := validate(txn, feeRate); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	} else if res.ID != txn.ID() || !res.MinFee.Equals(txn.MinerFee) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	// stale parent proofs are replaced by the current elements
+	stale := txn.DeepCopy()
+	stale.SiacoinInputs[0].Parent.StateElement.MerkleProof = nil
+	if res := validate(stale, feeRate); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+
+	// an unsigned transaction
+	if res := validate(resp.Transaction, feeRate); res.Valid || !hasIssue(res, CodeSignature) {
+		t.Fatalf("expected signature issue, got %v", res.Issues)
+	} else if issue := res.Issues[0]; issue.SiacoinInput == nil || *issue.SiacoinInput != 0 {
+		t.Fatal("expected the issue to name the first input")
+	}
+
+	// modifying an output unbalances the transaction and invalidates the
+	// signatures
+	modified := txn.DeepCopy()
+	modified.SiacoinOutputs[0].Value = types.Siacoins(26)
+	if res := validate(modified, feeRate); !hasIssue(res, CodeBalance) || !hasIssue(res, CodeSignature) {
+		t.Fatalf("expected balance and signature issues, got %v", res.Issues)
+	}
+
+	// a miner fee that wraps the output sum around to the input sum
+	wrapped := txn.DeepCopy()
+	wrapped.SiacoinOutputs[0].Value = types.Siacoins(40)
+	var outputSum types.Currency
+	for _, sco := range wrapped.SiacoinOutputs {
+		outputSum = outputSum.Add(sco.Value)
+	}
+	wrapped.MinerFee = types.MaxCurrency.Sub(outputSum).Add(types.Siacoins(30)).Add(types.NewCurrency64(1))
+	if res := validate(wrapped, feeRate); res.Valid || !hasIssue(res, CodeOverflow) {
+		t.Fatalf("expected overflow issue, got %v", res.Issues)
+	}
+
+	// siafund outputs that wrap around to the (empty) input sum
+	wrapped = txn.DeepCopy()
+	wrapped.SiafundOutputs = []types.SiafundOutput{{Address: addresses[0].Address, Value: math.MaxUint64}, {Address: addresses[0].Address, Value: 1}}
+	if res := validate(wrapped, feeRate); res.Valid || !hasIssue(res, CodeOverflow) {
+		t.Fatalf("expected overflow issue, got %v", res.Issues)
+	}
+
+	if res := validate(txn, feeRate.Mul64(2)); res.Valid || !hasIssue(res, CodeFee) {
+		t.Fatalf("expected fee issue, got %v", res.Issues)
+	}
+
+	b.SpendSiacoinElement(sces[0].ID)
+	if res := validate(txn, feeRate); res.Valid || !hasIssue(res, CodeMissingParent) {
+		t.Fatalf("expected missing parent issue, got %v", res.Issues)
+	}
+
+	n := *cs.Network
+	n.HardforkV2.AllowHeight = cs.Index.Height + 10
+	cs.Network = &n
+	if res := ValidateV2Transaction(cs, txn, sces, nil, types.ZeroCurrency); res.Valid || !hasIssue(res, CodeHardfork) {
+		t.Fatalf("expected hardfork issue, got %v", res.Issues)
+	}
+}
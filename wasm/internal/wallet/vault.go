@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.sia.tech/walletd/v2/wallet"
+)
+
+// MaxVaultScan is the maximum number of keys FindVaults checks at once.
+const MaxVaultScan = 10000
+
+type (
+	// A Vault is a v2 address that can only be spent by a wallet key once a
+	// height or time lock has passed.
+	Vault struct {
+		Index uint64 `json:"index"`
+		// UnlockHeight is the chain height that must be reached before the
+		// vault can be spent. The lock is checked against the parent state,
+		// so the first block that can include the spend is UnlockHeight+1.
+		UnlockHeight uint64 `json:"unlock_height,omitempty"`
+		// UnlockTime is the unix timestamp the median block timestamp must
+		// pass before the vault can be spent.
+		UnlockTime  int64             `json:"unlock_time,omitempty"`
+		Address     types.Address     `json:"address"`
+		Policy      types.SpendPolicy `json:"policy"`
+		Description string            `json:"description"`
+	}
+
+	// A VaultOutput is an unspent siacoin output sent to a vault.
+	VaultOutput struct {
+		SiacoinOutput
+		Index    uint64 `json:"index"`
+		Unlocked bool   `json:"unlocked"`
+	}
+)
+
+// vaultPolicy returns the policy requiring the public key and every lock.
+func vaultPolicy(pk types.PublicKey, unlockHeight uint64, unlockTime int64) types.SpendPolicy {
+	of := []types.SpendPolicy{types.PolicyPublicKey(pk)}
+	if unlockHeight != 0 {
+		of = append(of, types.PolicyAbove(unlockHeight))
+	}
+	if unlockTime != 0 {
+		of = append(of, types.PolicyAfter(time.Unix(unlockTime, 0)))
+	}
+	return types.PolicyThreshold(uint8(len(of)), of)
+}
+
+// NewVault derives the vault address of the key at index. At least one of
+// unlockHeight or unlockTime must be set. If both are set, both locks must
+// pass.
+func NewVault(seed *[32]byte, index uint64, unlockHeight uint64, unlockTime int64) (Vault, error) {
+	if unlockHeight == 0 && unlockTime == 0 {
+		return Vault{}, errors.New("vault must have an unlock height or time")
+	} else if unlockTime < 0 {
+		return Vault{}, errors.New("unlock time must be positive")
+	}
+
+	locks := make([]string, 0, 2)
+	if unlockHeight != 0 {
+		// the spend must be in a block after the unlock height
+		locks = append(locks, fmt.Sprintf("from block %d", unlockHeight+1))
+	}
+	if unlockTime != 0 {
+		locks = append(locks, "after "+time.Unix(unlockTime, 0).UTC().Format(time.RFC3339))
+	}
+
+	policy := vaultPolicy(wallet.KeyFromSeed(seed, index).PublicKey(), unlockHeight, unlockTime)
+	return Vault{
+		Index:        index,
+		UnlockHeight: unlockHeight,
+		UnlockTime:   unlockTime,
+		Address:      policy.Address(),
+		Policy:       policy,
+		Description:  fmt.Sprintf("key %d, spendable %s", index, strings.Join(locks, " and ")),
+	}, nil
+}
+
+// Unlocked returns true if the vault can be spent in the block after cs.
+func (v Vault) Unlocked(cs consensus.State) bool {
+	if cs.Index.Height < v.UnlockHeight {
+		return false
+	}
+	return v.UnlockTime == 0 || medianTimestamp(cs).After(time.Unix(v.UnlockTime, 0))
+}
+
+// FindVaults returns the vaults with the lock of the n keys starting at
+// index start that have been seen on chain. n must be between 1 and
+// MaxVaultScan.
+func FindVaults(w backend.Backend, seed *[32]byte, unlockHeight uint64, unlockTime int64, start uint64, n int) ([]Vault, error) {
+	if n < 1 || n > MaxVaultScan {
+		return nil, fmt.Errorf("key count must be between 1 and %d", MaxVaultScan)
+	}
+
+	candidates := make([]Vault, 0, n)
+	groups := make([][]types.Address, 0, n)
+	for i := start; len(candidates) < n; i++ {
+		v, err := NewVault(seed, i, unlockHeight, unlockTime)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, v)
+		groups = append(groups, []types.Address{v.Address})
+	}
+
+	used, err := findUsedGroups(context.Background(), w, groups)
+	if err != nil {
+		return nil, err
+	}
+	var found []Vault
+	for i, v := range candidates {
+		if used[i] {
+			found = append(found, v)
+		}
+	}
+	return found, nil
+}
+
+// VaultOutputs returns the unspent siacoin outputs of the vaults and whether
+// each can be spent in the block after cs.
+func VaultOutputs(w backend.Backend, cs consensus.State, vaults []Vault) ([]VaultOutput, error) {
+	byAddress := make(map[types.Address]Vault, len(vaults))
+	addresses := make([]types.Address, 0, len(vaults))
+	for _, v := range vaults {
+		byAddress[v.Address] = v
+		addresses = append(addresses, v.Address)
+	}
+
+	utxos, err := WalletSiacoinOutputs(w, addresses)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]VaultOutput, 0, len(utxos))
+	for _, sco := range utxos {
+		v := byAddress[sco.UnlockHash]
+		outputs = append(outputs, VaultOutput{
+			SiacoinOutput: sco,
+			Index:         v.Index,
+			Unlocked:      v.Unlocked(cs),
+		})
+	}
+	return outputs, nil
+}
+
+// SweepVaults returns a signed transaction sending every output in utxos
+// that belongs to one of the vaults to the destination address, less the
+// miner fee. Every spent vault must be unlocked in the block after cs.
+func SweepVaults(cs consensus.State, seed *[32]byte, vaults []Vault, utxos []types.SiacoinElement, destination types.Address, feeRate types.Currency) (types.V2Transaction, error) {
+	if err := CheckTransactionFormat(cs, FormatV2); err != nil {
+		return types.V2Transaction{}, err
+	}
+
+	byAddress := make(map[types.Address]Vault, len(vaults))
+	for _, v := range vaults {
+		pk := wallet.KeyFromSeed(seed, v.Index).PublicKey()
+		v.Policy = vaultPolicy(pk, v.UnlockHeight, v.UnlockTime)
+		if v.Address != v.Policy.Address() {
+			return types.V2Transaction{}, fmt.Errorf("vault %v was not derived from this seed", v.Address)
+		}
+		byAddress[v.Address] = v
+	}
+
+	var inputs []sweepInput
+	for _, sce := range utxos {
+		v, ok := byAddress[sce.SiacoinOutput.Address]
+		if !ok || sce.MaturityHeight > cs.Index.Height {
+			continue
+		} else if !v.Unlocked(cs) {
+			return types.V2Transaction{}, fmt.Errorf("vault %v is locked: %s", v.Address, v.Description)
+		}
+		inputs = append(inputs, sweepInput{
+			Parent: sce,
+			Policy: v.Policy,
+			Key:    wallet.KeyFromSeed(seed, v.Index),
+		})
+	}
+	if len(inputs) == 0 {
+		return types.V2Transaction{}, errors.New("no vault outputs to spend")
+	}
+	return sweepOutputs(cs, inputs, nil, destination, feeRate)
+}
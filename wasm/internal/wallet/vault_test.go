@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/backend"
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"lukechampine.com/frand"
+)
+
+func TestVault(t *testing.T) {
+	seed := testSeed(t)
+	base := testState(600000)
+	for i := range base.PrevTimestamps {
+		base.PrevTimestamps[i] = time.Now().Add(-2 * time.Hour)
+	}
+
+	v, err := NewVault(seed, 3, base.Index.Height+2, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if v.Address == GenerateAddress(seed, 3).Address {
+		t.Fatal("vault address should differ from the standard address")
+	} else if v.Description != "key 3, spendable from block 600003" {
+		t.Fatalf("unexpected description %q", v.Description)
+	}
+
+	cs, sces := testChainElements(base, types.SiacoinOutput{Address: v.Address, Value: types.Siacoins(10)})
+	b := backend.NewMemory(cs)
+	b.AddSiacoinElements(sces...)
+
+	// the vault is found by scanning the key indices with its lock
+	if found, err := FindVaults(b, seed, v.UnlockHeight, 0, 0, 10); err != nil {
+		t.Fatal(err)
+	} else if len(found) != 1 || found[0].Address != v.Address {
+		t.Fatalf("expected to find vault %v, got %v", v.Address, found)
+	}
+	for _, n := range []int{-1, 0, MaxVaultScan + 1} {
+		if _, err := FindVaults(b, seed, v.UnlockHeight, 0, 0, n); err == nil {
+			t.Fatalf("expected key count error for %d", n)
+		}
+	}
+
+	outputs, err := VaultOutputs(b, cs, []Vault{v})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(outputs) != 1 || outputs[0].Index != 3 || outputs[0].Unlocked {
+		t.Fatalf("expected one locked output, got %v", outputs)
+	}
+
+	destination := types.Address(frand.Entropy256())
+	feeRate := types.Siacoins(1).Div64(1000)
+	if _, err := SweepVaults(cs, seed, []Vault{v}, sces, destination, feeRate); err == nil {
+		t.Fatal("expected locked vault error")
+	}
+
+	// mine a block to pass the lock
+	b1 := types.Block{
+		ParentID:  cs.Index.ID,
+		Timestamp: time.Now(),
+		V2:        &types.V2BlockData{Height: cs.Index.Height + 1},
+	}
+	cs, au := consensus.ApplyBlock(cs, b1, consensus.V1BlockSupplement{}, time.Time{})
+	au.UpdateElementProof(&sces[0].StateElement)
+	if !v.Unlocked(cs) {
+		t.Fatal("expected vault to be unlocked")
+	}
+
+	txn, err := SweepVaults(cs, seed, []Vault{v}, sces, destination, feeRate)
+	if err != nil {
+		t.Fatal(err)
+	} else if res := ValidateV2Transaction(cs, txn, sces, nil, feeRate); !res.Valid {
+		t.Fatalf("expected valid transaction, got %v", res.Issues)
+	}
+
+	// time locks use the median timestamp of the previous blocks
+	if v, err := NewVault(seed, 0, 0, time.Now().Add(-time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	} else if v.Unlocked(cs) {
+		t.Fatal("expected time locked vault to be locked")
+	} else if v, err = NewVault(seed, 0, 0, time.Now().Add(-3*time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	} else if !v.Unlocked(cs) {
+		t.Fatal("expected time locked vault to be unlocked")
+	}
+
+	if _, err := NewVault(seed, 0, 0, 0); err == nil {
+		t.Fatal("expected missing lock error")
+	}
+}
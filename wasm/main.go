@@ -1,24 +1,62 @@
+//go:build js && wasm
+
 package main
 
 import (
-	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
-	"sort"
+	"net/url"
 	"strings"
+	"sync"
 	"syscall/js"
 	"time"
 
 	"github.com/siacentral/sia-lite-wallet-web/wasm/build"
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/network"
 	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/siad"
+	"github.com/siacentral/sia-lite-wallet-web/wasm/internal/wallet"
+	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
-	"go.sia.tech/coreutils/chain"
 	"go.sia.tech/walletd/v2/api"
-	"go.sia.tech/walletd/v2/wallet"
 )
 
-const SIASCAN_ADDRESS = "https://api.siascan.com/wallet"
+var (
+	// backendMu protects backendNetwork, backendAddress and backendPassword
+	backendMu       sync.Mutex
+	backendNetwork  = network.Default()
+	backendAddress  = backendNetwork.BackendAddress
+	backendPassword string
+)
+
+// currentNetwork returns the parameters of the configured network
+func currentNetwork() network.Parameters {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	return backendNetwork
+}
+
+// newClient returns a walletd API client for the configured backend
+func newClient() *api.Client {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	return api.NewClient(backendAddress, backendPassword)
+}
+
+// tipState returns the backend's current tip state after checking that the
+// backend is on the configured network
+func tipState(w *api.Client) (consensus.State, error) {
+	cs, err := w.ConsensusTipState()
+	if err != nil {
+		return consensus.State{}, fmt.Errorf("error getting consensus state: %w", err)
+	} else if err := currentNetwork().CheckNetwork(cs.Network); err != nil {
+		return consensus.State{}, err
+	}
+	return cs, nil
+}
 
 func main() {
 	log.Printf("starting sia wasm %s", build.Revision())
@@ -26,8 +64,13 @@ func main() {
 		"build": map[string]any{
 			"revision":  build.Revision(),
 			"timestamp": build.Time().Format(time.UnixDate),
+			"network":   network.Default().Name,
 		},
+		"configure":           js.FuncOf(configure),
 		"generateSeed":        js.FuncOf(generateSeed),
+		"validatePhrase":      js.FuncOf(validatePhrase),
+		"repairPhrase":        js.FuncOf(repairPhrase),
+		"convertPhrase":       js.FuncOf(convertPhrase),
 		"generateAddresses":   js.FuncOf(generateAddresses),
 		"recoverAddresses":    js.FuncOf(recoverAddresses),
 		"getTransactions":     js.FuncOf(getTransactions),
@@ -37,6 +80,46 @@ func main() {
 		"encodeUnlockHash":    js.FuncOf(encodeUnlockHash),
 		"v2InputSigHash":      js.FuncOf(v2InputSigHash),
 		"v2SignTransaction":   js.FuncOf(v2SignTransaction),
+
+		"buildV2Transaction":      js.FuncOf(buildV2Transaction),
+		"planConsolidation":       js.FuncOf(planConsolidation),
+		"sendSiacoins":            js.FuncOf(sendSiacoins),
+		"validateV2Transaction":   js.FuncOf(validateV2Transaction),
+		"decodeTransaction":       js.FuncOf(decodeTransaction),
+		"decodeV2Transaction":     js.FuncOf(decodeV2Transaction),
+		"encodeV2TransactionFull": js.FuncOf(encodeV2TransactionFull),
+
+		"newMultisig":             js.FuncOf(newMultisig),
+		"exportPublicKey":         js.FuncOf(exportPublicKey),
+		"addMultisigSignature":    js.FuncOf(addMultisigSignature),
+		"mergeMultisigSignatures": js.FuncOf(mergeMultisigSignatures),
+		"finalizeMultisig":        js.FuncOf(finalizeMultisig),
+
+		"newVault":        js.FuncOf(newVault),
+		"findVaults":      js.FuncOf(findVaults),
+		"getVaultOutputs": js.FuncOf(getVaultOutputs),
+		"sweepVaults":     js.FuncOf(sweepVaults),
+
+		"newHTLC":          js.FuncOf(newHTLC),
+		"newPreimage":      js.FuncOf(newPreimage),
+		"claimHTLC":        js.FuncOf(claimHTLC),
+		"refundHTLC":       js.FuncOf(refundHTLC),
+		"findHTLCPreimage": js.FuncOf(findHTLCPreimage),
+
+		"signMessage":   js.FuncOf(signMessage),
+		"verifyMessage": js.FuncOf(verifyMessage),
+
+		"proveReserves":      js.FuncOf(proveReserves),
+		"verifyReserveProof": js.FuncOf(verifyReserveProof),
+
+		"createPartialTransaction":   js.FuncOf(createPartialTransaction),
+		"inspectPartialTransaction":  js.FuncOf(inspectPartialTransaction),
+		"signPartialTransaction":     js.FuncOf(signPartialTransaction),
+		"combinePartialTransactions": js.FuncOf(combinePartialTransactions),
+		"finalizePartialTransaction": js.FuncOf(finalizePartialTransaction),
+
+		"signTransactionWithState":   js.FuncOf(signTransactionWithState),
+		"v2SignTransactionWithState": js.FuncOf(v2SignTransactionWithState),
 	})
 
 	c := make(chan bool, 1)
@@ -70,6 +153,72 @@ func interfaceToJSON(obj any) (con map[string]any, err error) {
 	return
 }
 
+func jsIndices(v js.Value) []uint64 {
+	indices := make([]uint64, v.Length())
+	for i := range indices {
+		indices[i] = uint64(v.Index(i).Int())
+	}
+	return indices
+}
+
+// jsKeySource returns the key source of a signing options object. ok is false
+// if v is an array of key indices instead.
+func jsKeySource(v js.Value) (ks wallet.KeySource, ok bool, err error) {
+	if js.Global().Get("Array").Call("isArray", v).Bool() {
+		return wallet.KeySource{}, false, nil
+	}
+	buf := js.Global().Get("JSON").Call("stringify", v).String()
+	if err := json.Unmarshal([]byte(buf), &ks); err != nil {
+		return wallet.KeySource{}, false, fmt.Errorf("error parsing signing options: %w", err)
+	}
+	return ks, true, nil
+}
+
+// parseSigningState parses a JSON signing state. The configured network is
+// used if the state does not specify one.
+func parseSigningState(jsonState string) (consensus.State, error) {
+	var ss network.SigningState
+	if err := json.Unmarshal([]byte(jsonState), &ss); err != nil {
+		return consensus.State{}, fmt.Errorf("error parsing consensus state: %w", err)
+	}
+	return ss.ConsensusState(currentNetwork().Name)
+}
+
+// jsBytes returns the bytes of a Uint8Array or of a string in the given
+// encoding
+func jsBytes(v js.Value, encoding string) ([]byte, error) {
+	switch {
+	case v.Type() == js.TypeString:
+		return wallet.DecodeString(v.String(), encoding)
+	case v.InstanceOf(js.Global().Get("Uint8Array")):
+		buf := make([]byte, v.Length())
+		js.CopyBytesToGo(buf, v)
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("expected Uint8Array or string, got %s", v.Type())
+	}
+}
+
+// jsPartialTransaction decodes a partial transaction from a Uint8Array or a
+// base64 encoded string
+func jsPartialTransaction(v js.Value) (wallet.PartialTransaction, error) {
+	buf, err := jsBytes(v, wallet.EncodingBase64)
+	if err != nil {
+		return wallet.PartialTransaction{}, err
+	}
+	return wallet.DecodePartialTransaction(buf)
+}
+
+// encodePartialTransaction returns the base64 encoding of a partial
+// transaction
+func encodePartialTransaction(p wallet.PartialTransaction) (string, error) {
+	buf, err := wallet.EncodePartialTransaction(p)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
 func jsArray[T any](b []T) any {
 	jsb := make([]any, 0, len(b))
 	for _, v := range b {
@@ -78,6 +227,51 @@ func jsArray[T any](b []T) any {
 	return jsb
 }
 
+func configure(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	networkName := strings.TrimSpace(args[0].String())
+	address := strings.TrimRight(strings.TrimSpace(args[1].String()), "/")
+	password := args[2].String()
+	callback := args[3]
+
+	params, err := network.Lookup(networkName)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	if len(address) == 0 {
+		address = params.BackendAddress
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("invalid backend address: %s", err), js.Null())
+		return nil
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		callback.Invoke(fmt.Sprintf("invalid backend address: unsupported scheme %q", u.Scheme), js.Null())
+		return nil
+	} else if len(u.Host) == 0 {
+		callback.Invoke("invalid backend address: missing host", js.Null())
+		return nil
+	}
+
+	backendMu.Lock()
+	backendNetwork = params
+	backendAddress = address
+	backendPassword = password
+	backendMu.Unlock()
+
+	callback.Invoke(js.Null(), map[string]any{
+		"network": params.Name,
+		"address": address,
+	})
+	return nil
+}
+
 func encodeTransaction(this js.Value, args []js.Value) any {
 	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
 		return err.Error()
@@ -93,16 +287,14 @@ func encodeTransaction(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	buf := bytes.NewBuffer(nil)
-	enc := types.NewEncoder(buf)
-	txn.EncodeTo(enc)
-	if err := enc.Flush(); err != nil {
+	buf, err := wallet.EncodeTransaction(txn)
+	if err != nil {
 		callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
 		return err.Error()
 	}
 	// not sure why it's necessary to convert bytes
 	// to []any, but it is
-	callback.Invoke(js.Null(), jsArray(buf.Bytes()))
+	callback.Invoke(js.Null(), jsArray(buf))
 
 	return nil
 }
@@ -121,14 +313,36 @@ func encodeV2Transaction(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	buf := bytes.NewBuffer(nil)
-	enc := types.NewEncoder(buf)
-	types.V2TransactionSemantics(txn).EncodeTo(enc)
-	if err := enc.Flush(); err != nil {
+	buf, err := wallet.EncodeV2Transaction(txn)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
+		return err.Error()
+	}
+	callback.Invoke(js.Null(), jsArray(buf))
+
+	return nil
+}
+
+func encodeV2TransactionFull(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonTxn := args[0].String()
+	callback := args[1]
+
+	var txn types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	buf, err := wallet.EncodeV2TransactionFull(txn)
+	if err != nil {
 		callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
 		return err.Error()
 	}
-	callback.Invoke(js.Null(), jsArray(buf.Bytes()))
+	callback.Invoke(js.Null(), jsArray(buf))
 
 	return nil
 }
@@ -138,13 +352,22 @@ func signTransaction(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	w := api.NewClient(SIASCAN_ADDRESS, "")
+	w := newClient()
 
 	phrase := args[0].String()
 	jsonTxn := args[1].String()
-	sigIndicesLen := args[2].Length()
 	callback := args[3]
 
+	keySource, inferIndices, err := jsKeySource(args[2])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+	var sigIndices []uint64
+	if !inferIndices {
+		sigIndices = jsIndices(args[2])
+	}
+
 	var txn types.Transaction
 	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
 		callback.Invoke(err.Error(), js.Null())
@@ -152,26 +375,45 @@ func signTransaction(this js.Value, args []js.Value) any {
 	}
 
 	go func() {
-		cs, err := w.ConsensusTipState()
+		cs, err := tipState(w)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting consensus state: %s", err), js.Null())
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
 
 		var seed [32]byte
 		defer clear(seed[:])
-		if err := phraseToSeed(phrase, &seed); err != nil {
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
 			callback.Invoke(err.Error(), js.Null())
 			return
 		}
 
-		for i := range sigIndicesLen {
-			index := uint64(args[2].Index(i).Int())
+		if inferIndices {
+			keys, err := keySource.KeyIndex(&seed)
+			if err != nil {
+				callback.Invoke(err.Error(), js.Null())
+				return
+			}
+			unsigned, err := wallet.SignTransactionWithKeys(cs, &seed, &txn, keys)
+			if err != nil {
+				callback.Invoke(err.Error(), js.Null())
+				return
+			}
+			obj, err := interfaceToJSON(map[string]any{
+				"transaction":     txn,
+				"unsigned_inputs": unsigned,
+			})
+			if err != nil {
+				callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+				return
+			}
+			callback.Invoke(js.Null(), obj)
+			return
+		}
 
-			sigHash := cs.WholeSigHash(txn, txn.Signatures[i].ParentID, 0, 0, nil)
-			sk := wallet.KeyFromSeed(&seed, index)
-			sig := sk.SignHash(sigHash)
-			txn.Signatures[i].Signature = sig[:]
+		if err := wallet.SignTransaction(cs, &seed, &txn, sigIndices); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
 
 		obj, err := interfaceToJSON(txn)
@@ -213,59 +455,129 @@ func generateSeed(this js.Value, args []js.Value) any {
 	seedType := args[0].String()
 	callback := args[1]
 
-	switch seedType {
-	case "walrus":
-		callback.Invoke(js.Null(), wallet.NewSeedPhrase())
-	case "sia":
-		callback.Invoke(js.Null(), siad.NewSeedPhrase())
-	default:
-		callback.Invoke(fmt.Sprintf("unknown seed type: %q", seedType), js.Null())
+	phrase, err := wallet.NewSeedPhrase(seedType)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), phrase)
+	return nil
+}
+
+func validatePhrase(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
+	phrase := args[0].String()
+	callback := args[1]
+
+	resp, err := interfaceToJSON(wallet.ValidatePhrase(phrase))
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), resp)
 	return nil
 }
 
-func phraseToSeed(phrase string, seed *[32]byte) error {
-	switch len(strings.Fields(phrase)) {
-	case 28, 29:
-		return siad.SeedFromPhrase(seed, phrase)
-	case 12:
-		return wallet.SeedFromPhrase(seed, phrase)
-	default:
-		return fmt.Errorf("invalid seed phrase length: %d words", len(strings.Fields(phrase)))
+func repairPhrase(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	phrase := args[0].String()
+	confirm := args[1].Int()
+	callback := args[2]
+
+	if confirm < 0 {
+		callback.Invoke("confirm addresses must not be negative", js.Null())
+		return nil
 	}
+
+	go func() {
+		w := newClient()
+
+		// the context is never cancelled; JS cancels the search by
+		// terminating the worker when the signal passed to repairPhrase is
+		// aborted
+		candidates, err := wallet.RepairPhrase(context.Background(), w, phrase, wallet.RepairOptions{ConfirmAddresses: uint64(confirm)}, func(progress wallet.RepairProgress) {
+			data, err := interfaceToJSON(progress)
+			if err != nil {
+				return
+			}
+			callback.Invoke("progress", data)
+		})
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		resp, err := interfaceToJSON(map[string]any{"candidates": candidates})
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), resp)
+	}()
+	return nil
 }
 
-func generateAddress(seed *[32]byte, i uint64) map[string]any {
-	sk := wallet.KeyFromSeed(seed, uint64(i))
-	return map[string]any{
-		"unlock_conditions": types.StandardUnlockConditions(sk.PublicKey()),
-		"usage_type":        "sent",
-		"address":           types.StandardUnlockHash(sk.PublicKey()).String(),
-		"index":             i,
+func convertPhrase(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	phrase := args[0].String()
+	seedType := args[1].String()
+	language := siad.Language(args[2].String())
+	callback := args[3]
+
+	conv, err := wallet.ConvertPhrase(phrase, seedType, language)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	resp, err := interfaceToJSON(conv)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
 	}
+	callback.Invoke(js.Null(), resp)
+	return nil
 }
 
 func generateAddresses(this js.Value, args []js.Value) any {
-	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeNumber, js.TypeFunction); err != nil {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeNumber, js.TypeString, js.TypeFunction); err != nil {
 		return err.Error()
 	}
 
 	phrase := args[0].String()
 	i := uint64(args[1].Int())
 	n := args[2].Int()
-	callback := args[3]
+	addressTypeStr := args[3].String()
+	callback := args[4]
+
+	var addressType wallet.AddressType
+	if err := addressType.UnmarshalText([]byte(addressTypeStr)); err != nil {
+		return err.Error()
+	}
 
 	var seed [32]byte
 	defer clear(seed[:])
 
-	if err := phraseToSeed(phrase, &seed); err != nil {
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
 		return err.Error()
 	}
 
+	generated, err := wallet.GenerateTypedAddresses(&seed, i, n, addressType)
+	if err != nil {
+		return err.Error()
+	}
 	addresses := make([]any, 0, n)
-	for ; n > len(addresses); i++ {
-		obj, err := interfaceToJSON(generateAddress(&seed, i))
+	for _, addr := range generated {
+		obj, err := interfaceToJSON(addr)
 		if err != nil {
 			return err.Error()
 		}
@@ -281,7 +593,7 @@ func v2InputSigHash(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	w := api.NewClient(SIASCAN_ADDRESS, "")
+	w := newClient()
 	jsonTxn := args[0].String()
 	callback := args[1]
 
@@ -291,9 +603,9 @@ func v2InputSigHash(this js.Value, args []js.Value) any {
 	}
 
 	go func() {
-		cs, err := w.ConsensusTipState()
+		cs, err := tipState(w)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting consensus state: %s", err), js.Null())
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
 
@@ -308,15 +620,20 @@ func v2SignTransaction(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	w := api.NewClient(SIASCAN_ADDRESS, "")
+	w := newClient()
 
 	phrase := args[0].String()
 	jsonTxn := args[1].String()
-	sigIndices := make([]uint64, args[2].Length())
 	callback := args[3]
 
-	for i := range sigIndices {
-		sigIndices[i] = uint64(args[2].Index(i).Int())
+	keySource, inferIndices, err := jsKeySource(args[2])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+	var sigIndices []uint64
+	if !inferIndices {
+		sigIndices = jsIndices(args[2])
 	}
 
 	var txn types.V2Transaction
@@ -325,45 +642,52 @@ func v2SignTransaction(this js.Value, args []js.Value) any {
 		return err.Error()
 	}
 
-	if len(sigIndices) != len(txn.SiacoinInputs)+len(txn.SiafundInputs) {
+	if !inferIndices && len(sigIndices) != len(txn.SiacoinInputs)+len(txn.SiafundInputs) {
 		err := fmt.Errorf("expected %d signatures, got %d", len(txn.SiacoinInputs)+len(txn.SiafundInputs), len(sigIndices))
 		callback.Invoke(err.Error(), js.Null())
 		return err.Error()
 	}
 
 	go func() {
-		cs, err := w.ConsensusTipState()
+		cs, err := tipState(w)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting consensus state: %s", err), js.Null())
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
-		sigHash := cs.InputSigHash(txn)
 
 		var seed [32]byte
 		defer clear(seed[:])
-		if err := phraseToSeed(phrase, &seed); err != nil {
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
 			callback.Invoke(err.Error(), js.Null())
 			return
 		}
 
-		for i := range txn.SiacoinInputs {
-			// pop the first index from sigIndices
-			index := sigIndices[0]
-			sigIndices = sigIndices[1:]
-			// sign the input
-			sk := wallet.KeyFromSeed(&seed, index)
-			sig := sk.SignHash(sigHash)
-			txn.SiacoinInputs[i].SatisfiedPolicy.Signatures = []types.Signature{sig}
+		if inferIndices {
+			keys, err := keySource.KeyIndex(&seed)
+			if err != nil {
+				callback.Invoke(err.Error(), js.Null())
+				return
+			}
+			unsigned, err := wallet.SignV2TransactionWithKeys(cs, &seed, &txn, keys)
+			if err != nil {
+				callback.Invoke(err.Error(), js.Null())
+				return
+			}
+			obj, err := interfaceToJSON(map[string]any{
+				"transaction":     txn,
+				"unsigned_inputs": unsigned,
+			})
+			if err != nil {
+				callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+				return
+			}
+			callback.Invoke(js.Null(), obj)
+			return
 		}
 
-		for i := range txn.SiafundInputs {
-			// pop the first index from sigIndices
-			index := sigIndices[0]
-			sigIndices = sigIndices[1:]
-			// sign the input
-			sk := wallet.KeyFromSeed(&seed, index)
-			sig := sk.SignHash(sigHash)
-			txn.SiafundInputs[i].SatisfiedPolicy.Signatures = []types.Signature{sig}
+		if err := wallet.SignV2Transaction(cs, &seed, &txn, sigIndices); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
 
 		obj, err := interfaceToJSON(txn)
@@ -388,469 +712,1327 @@ func recoverAddresses(this js.Value, args []js.Value) any {
 	callback := args[4]
 
 	var seed [32]byte
-	if err := phraseToSeed(phrase, &seed); err != nil {
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
 		return err.Error()
 	}
 
 	go func() {
-		w := api.NewClient(SIASCAN_ADDRESS, "")
-
-		var gap uint64
-		n := min(500, lookahead)
-		addresses := make([]types.Address, 0, n)
-		lastSeenIndex := lastKnownIndex
-		for i := startIndex; gap < lookahead; i += n {
-			addresses = addresses[:0] // reset addresses slice
-			recovered := make([]map[string]any, 0, n)
-			start, end := i, i+n
-			for i := start; i < end; i++ {
-				addresses = append(addresses, types.StandardUnlockHash(wallet.KeyFromSeed(&seed, i).PublicKey()))
-				recovered = append(recovered, generateAddress(&seed, i))
-			}
+		defer clear(seed[:])
 
-			log.Println("checking addresses from", i, "to", i+uint64(n))
-			ok, err := w.CheckAddresses(addresses)
-			if err != nil {
-				callback.Invoke(fmt.Sprintf("error checking addresses: %s", err), js.Null())
-				return
-			} else if !ok {
-				// if no used addresses are found, increase the gap
-				gap += uint64(n)
-				recovered = recovered[:0] // reset recovered addresses
-				log.Printf("no used addresses found, increasing gap to %d", gap)
-			} else {
-				// reset gap if used addresses are found
-				lastSeenIndex = end
-				gap = 0
-				log.Println("found used addresses, gap reset")
-			}
+		w := newClient()
 
+		var encodeErr error
+		err := wallet.RecoverAddresses(w, &seed, startIndex, lookahead, lastKnownIndex, func(progress wallet.RecoveryProgress) {
 			// send progress callback
-			data, err := interfaceToJSON(map[string]any{
-				"found":     len(recovered),
-				"addresses": recovered,
-				"index":     lastSeenIndex,
-			})
+			data, err := interfaceToJSON(progress)
 			if err != nil {
-				callback.Invoke(fmt.Sprintf("error encoding addresses: %s", err), js.Null())
+				encodeErr = err
 				return
 			}
 			callback.Invoke("progress", data)
+		})
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		} else if encodeErr != nil {
+			callback.Invoke(fmt.Sprintf("error encoding addresses: %s", encodeErr), js.Null())
+			return
 		}
-
-		// if any used addresses are found, reset gap
-		gap = 0
 		callback.Invoke(js.Null(), js.Null())
 	}()
 	return nil
 }
 
-type (
-	siacoinOutput struct {
-		OutputID   types.SiacoinOutputID `json:"output_id"`
-		UnlockHash types.Address         `json:"unlock_hash"`
-		Value      types.Currency        `json:"value"`
+func getTransactions(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeObject, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	siafundOutput struct {
-		OutputID   types.SiafundOutputID `json:"output_id"`
-		UnlockHash types.Address         `json:"unlock_hash"`
-		Value      uint64                `json:"value"`
+	count := args[0].Length()
+	callback := args[1]
+
+	go func() {
+		w := newClient()
+
+		// each address is either a plain address or a JSON encoded wallet
+		// address. Wallet addresses are expanded to both of their key's
+		// address types so funds sent to either are found.
+		var addresses []types.Address
+		var walletAddresses []wallet.Address
+		for i := range count {
+			str := args[0].Index(i).String()
+			if strings.HasPrefix(str, "{") {
+				var addr wallet.Address
+				if err := json.Unmarshal([]byte(str), &addr); err != nil {
+					callback.Invoke(fmt.Sprintf("error parsing address %d: %s", i, err), js.Null())
+					return
+				}
+				walletAddresses = append(walletAddresses, addr)
+				continue
+			}
+
+			var addr types.Address
+			if err := addr.UnmarshalText([]byte(str)); err != nil {
+				callback.Invoke(fmt.Sprintf("error parsing address %d: %s", i, err), js.Null())
+				return
+			}
+			addresses = append(addresses, addr)
+		}
+		seen := make(map[types.Address]bool, len(addresses))
+		for _, addr := range addresses {
+			seen[addr] = true
+		}
+		for _, addr := range wallet.ScanAddresses(walletAddresses) {
+			if !seen[addr] {
+				addresses = append(addresses, addr)
+			}
+		}
+
+		walletResp, err := wallet.WalletSummary(w, addresses)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		obj, err := interfaceToJSON(walletResp)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding wallet response: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+
+	return nil
+}
+
+func signTransactionWithState(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeObject, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	walletBalance struct {
-		SiafundClaim            types.Currency         `json:"siafund_claim"`
-		Transactions            []processedTransaction `json:"transactions"`
-		UnspentSiacoinOutputs   []siacoinOutput        `json:"unspent_siacoin_outputs"`
-		UnspentSiafundOutputs   []siafundOutput        `json:"unspent_siafund_outputs"`
-		ConfirmedSiacoinBalance types.Currency         `json:"confirmed_siacoin_balance"`
-		ConfirmedSiafundBalance uint64                 `json:"confirmed_siafund_balance"`
-		UnconfirmedSiacoinDelta types.Currency         `json:"unconfirmed_siacoin_delta"`
-		UnconfirmedSiafundDelta types.Currency         `json:"unconfirmed_siafund_delta"`
+	phrase := args[0].String()
+	jsonTxn := args[1].String()
+	sigIndices := jsIndices(args[2])
+	jsonState := args[3].String()
+	callback := args[4]
+
+	var txn types.Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
 	}
 
-	processedTransaction struct {
-		ID             types.Hash256  `json:"id"`
-		BlockHeight    uint64         `json:"block_height"`
-		Confirmations  uint64         `json:"confirmations"`
-		Timestamp      time.Time      `json:"timestamp"`
-		Fees           types.Currency `json:"fees"`
-		SiacoinInputs  types.Currency `json:"siacoin_inputs"`
-		SiacoinOutputs types.Currency `json:"siacoin_outputs"`
-		SiafundInputs  uint64         `json:"siafund_inputs"`
-		SiafundOutputs uint64         `json:"siafund_outputs"`
-		Tags           []string       `json:"tags,omitempty"`
+	cs, err := parseSigningState(jsonState)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
 	}
-)
 
-func hasAnnouncement(txn types.Transaction) bool {
-	for _, arb := range txn.ArbitraryData {
-		var ha chain.HostAnnouncement
-		if ha.FromArbitraryData(arb) {
-			return true
-		}
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
 	}
-	return false
-}
 
-func hasV2Announcement(txn types.V2Transaction) bool {
-	for _, attestation := range txn.Attestations {
-		var ha chain.V2HostAnnouncement
-		if err := ha.FromAttestation(attestation); err == nil {
-			return true
-		}
+	if err := wallet.SignTransaction(cs, &seed, &txn, sigIndices); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
 	}
-	return false
-}
 
-func getWalletTransactions(w *api.Client, addresses []types.Address) ([]processedTransaction, error) {
-	if len(addresses) == 0 {
-		return nil, nil
+	obj, err := interfaceToJSON(txn)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+		return nil
 	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
 
-	relevantAddresses := make(map[types.Address]bool, len(addresses))
-	for _, addr := range addresses {
-		relevantAddresses[addr] = true
+func v2SignTransactionWithState(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeObject, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	processEvent := func(event wallet.Event) (processedTransaction, bool) {
-		processed := processedTransaction{
-			ID:            event.ID,
-			Timestamp:     event.Timestamp,
-			BlockHeight:   event.Index.Height,
-			Confirmations: event.Confirmations,
-		}
-		var relevant bool
-		switch data := event.Data.(type) {
-		case wallet.EventV1Transaction:
-			switch {
-			case len(data.Transaction.FileContracts) > 0 && len(data.Transaction.FileContractRevisions) > 0:
-				processed.Tags = append(processed.Tags, "contract_renewal")
-			case len(data.Transaction.FileContractRevisions) > 0:
-				processed.Tags = append(processed.Tags, "contract_revision")
-			case len(data.Transaction.FileContracts) > 0:
-				processed.Tags = append(processed.Tags, "contract_formation")
-			case len(data.Transaction.StorageProofs) > 0:
-				processed.Tags = append(processed.Tags, "storage_proof")
-			case hasAnnouncement(data.Transaction):
-				processed.Tags = append(processed.Tags, "host_announcement")
-			case max(len(data.Transaction.SiafundInputs), len(data.Transaction.SiafundOutputs)) > 0:
-				processed.Tags = append(processed.Tags, "siafund_transaction")
-			case max(len(data.Transaction.SiacoinInputs), len(data.Transaction.SiacoinOutputs)) > 0:
-				processed.Tags = append(processed.Tags, "siacoin_transaction")
-			}
-			for _, sce := range data.SpentSiacoinElements {
-				if !relevantAddresses[sce.SiacoinOutput.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiacoinInputs = processed.SiacoinInputs.Add(sce.SiacoinOutput.Value)
-			}
-			for _, sco := range data.Transaction.SiacoinOutputs {
-				if !relevantAddresses[sco.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiacoinOutputs = processed.SiacoinOutputs.Add(sco.Value)
-			}
-			for _, sfe := range data.SpentSiafundElements {
-				if !relevantAddresses[sfe.SiafundOutput.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiafundInputs += sfe.SiafundOutput.Value
-			}
-			for _, sfo := range data.Transaction.SiafundOutputs {
-				if !relevantAddresses[sfo.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiafundOutputs += sfo.Value
-			}
-		case wallet.EventV2Transaction:
-			switch {
-			case len(data.FileContractRevisions) > 0:
-				processed.Tags = append(processed.Tags, "contract_revision")
-			case len(data.FileContracts) > 0:
-				processed.Tags = append(processed.Tags, "contract_formation")
-			case len(data.FileContractResolutions) > 0:
-				fce := data.FileContractResolutions[0].Parent
-				switch res := data.FileContractResolutions[0].Resolution.(type) {
-				case *types.V2StorageProof:
-					processed.Tags = append(processed.Tags, "storage_proof")
-				case *types.V2FileContractRenewal:
-					if res.NewContract.ProofHeight == fce.V2FileContract.ProofHeight && res.NewContract.ExpirationHeight == fce.V2FileContract.ExpirationHeight {
-						processed.Tags = append(processed.Tags, "contract_refresh")
-					} else {
-						processed.Tags = append(processed.Tags, "contract_renewal")
-					}
-				case *types.V2FileContractExpiration:
-					processed.Tags = append(processed.Tags, "contract_expiration")
-				default:
-					processed.Tags = append(processed.Tags, "contract_resolution")
-				}
-			case hasV2Announcement(types.V2Transaction(data)):
-				processed.Tags = append(processed.Tags, "host_announcement")
-			case max(len(data.SiafundInputs), len(data.SiafundOutputs)) > 0:
-				processed.Tags = append(processed.Tags, "siafund_transaction")
-			case max(len(data.SiacoinInputs), len(data.SiacoinOutputs)) > 0:
-				processed.Tags = append(processed.Tags, "siacoin_transaction")
-			}
-			for _, sci := range data.SiacoinInputs {
-				if !relevantAddresses[sci.Parent.SiacoinOutput.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiacoinInputs = processed.SiacoinInputs.Add(sci.Parent.SiacoinOutput.Value)
-			}
-			for _, sco := range data.SiacoinOutputs {
-				if !relevantAddresses[sco.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiacoinOutputs = processed.SiacoinOutputs.Add(sco.Value)
-			}
-			for _, sfi := range data.SiafundInputs {
-				if !relevantAddresses[sfi.Parent.SiafundOutput.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiafundInputs += sfi.Parent.SiafundOutput.Value
-			}
-			for _, sfo := range data.SiafundOutputs {
-				if !relevantAddresses[sfo.Address] {
-					continue
-				}
-				relevant = true
-				processed.SiafundOutputs += sfo.Value
-			}
-		case wallet.EventV1ContractResolution:
-			relevant = true
-			if data.Missed {
-				processed.Tags = append(processed.Tags, "contract_missed_output")
-			} else {
-				processed.Tags = append(processed.Tags, "contract_valid_output")
-			}
-			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
-		case wallet.EventV2ContractResolution:
-			if data.SiacoinElement.SiacoinOutput.Value.IsZero() {
-				break
-			}
-			relevant = true
-			if data.Missed {
-				processed.Tags = append(processed.Tags, "contract_missed_output")
-			} else {
-				processed.Tags = append(processed.Tags, "contract_valid_output")
+	phrase := args[0].String()
+	jsonTxn := args[1].String()
+	sigIndices := jsIndices(args[2])
+	jsonState := args[3].String()
+	callback := args[4]
+
+	var txn types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	cs, err := parseSigningState(jsonState)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	if err := wallet.SignV2Transaction(cs, &seed, &txn, sigIndices); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(txn)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func decodeTransaction(this js.Value, args []js.Value) any {
+	if len(args) != 3 || args[1].Type() != js.TypeString || args[2].Type() != js.TypeFunction {
+		return "expected input, encoding and callback"
+	}
+
+	encoding := args[1].String()
+	callback := args[2]
+
+	buf, err := jsBytes(args[0], encoding)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	decoded, err := wallet.DecodeTransaction(buf)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(decoded)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding decoded transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func decodeV2Transaction(this js.Value, args []js.Value) any {
+	if len(args) != 3 || args[1].Type() != js.TypeString || args[2].Type() != js.TypeFunction {
+		return "expected input, encoding and callback"
+	}
+
+	encoding := args[1].String()
+	callback := args[2]
+
+	buf, err := jsBytes(args[0], encoding)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	decoded, err := wallet.DecodeV2Transaction(buf)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(decoded)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding decoded transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func buildV2Transaction(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonReq := args[0].String()
+	callback := args[1]
+
+	var req wallet.BuildRequest
+	if err := json.Unmarshal([]byte(jsonReq), &req); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing request: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		w := newClient()
+
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		addresses := make([]types.Address, 0, len(req.Addresses))
+		for _, addr := range req.Addresses {
+			addresses = append(addresses, addr.Address)
+		}
+		basis, utxos, err := wallet.SpendableSiacoinElements(w, addresses)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		resp, err := wallet.BuildV2Transaction(cs, utxos, req)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+		// the parent elements' proofs are relative to the basis they were
+		// fetched at
+		resp.Basis = basis
+
+		obj, err := interfaceToJSON(resp)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func planConsolidation(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonReq := args[0].String()
+	callback := args[1]
+
+	var req wallet.ConsolidationRequest
+	if err := json.Unmarshal([]byte(jsonReq), &req); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing request: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		w := newClient()
+
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		addresses := make([]types.Address, 0, len(req.Addresses))
+		for _, addr := range req.Addresses {
+			addresses = append(addresses, addr.Address)
+		}
+		basis, utxos, err := wallet.SpendableSiacoinElements(w, addresses)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		plan, err := wallet.PlanConsolidation(cs, utxos, req)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+		for i := range plan.Transactions {
+			plan.Transactions[i].Basis = basis
+		}
+
+		obj, err := interfaceToJSON(plan)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding consolidation plan: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func validateV2Transaction(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonTxn := args[0].String()
+	feeRateStr := args[1].String()
+	callback := args[2]
+
+	var txn types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing transaction: %s", err), js.Null())
+		return err.Error()
+	}
+
+	var minFeeRate types.Currency
+	if len(feeRateStr) != 0 {
+		if err := minFeeRate.UnmarshalText([]byte(feeRateStr)); err != nil {
+			callback.Invoke(fmt.Sprintf("error parsing fee rate: %s", err), js.Null())
+			return err.Error()
+		}
+	}
+
+	go func() {
+		w := newClient()
+
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		basis, sces, sfes, err := wallet.FetchV2Parents(w, txn)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		} else if basis != cs.Index {
+			callback.Invoke("chain tip changed while fetching parents, try again", js.Null())
+			return
+		}
+
+		obj, err := interfaceToJSON(wallet.ValidateV2Transaction(cs, txn, sces, sfes, minFeeRate))
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding validation result: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func sendSiacoins(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	phrase := args[0].String()
+	jsonReq := args[1].String()
+	callback := args[2]
+
+	var req wallet.BuildRequest
+	if err := json.Unmarshal([]byte(jsonReq), &req); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing request: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		w := newClient()
+
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		addresses := make([]types.Address, 0, len(req.Addresses))
+		for _, addr := range req.Addresses {
+			addresses = append(addresses, addr.Address)
+		}
+		basis, utxos, err := wallet.SpendableSiacoinElements(w, addresses)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		signed, err := wallet.BuildSignedTransaction(cs, &seed, utxos, req)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+		signed.Basis = basis
+
+		obj, err := interfaceToJSON(signed)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func newMultisig(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeNumber, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	required := args[0].Int()
+	jsonKeys := args[1].String()
+	callback := args[2]
+
+	if required < 1 || required > 255 {
+		callback.Invoke("required signatures must be between 1 and 255", js.Null())
+		return nil
+	}
+
+	var keys []types.PublicKey
+	if err := json.Unmarshal([]byte(jsonKeys), &keys); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing public keys: %s", err), js.Null())
+		return err.Error()
+	}
+
+	ms, err := wallet.NewMultisig(uint8(required), keys)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(ms)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding multisig: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func exportPublicKey(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	phrase := args[0].String()
+	index := uint64(args[1].Int())
+	callback := args[2]
+
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	callback.Invoke(js.Null(), wallet.ExportPublicKey(&seed, index).String())
+	return nil
+}
+
+func addMultisigSignature(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	w := newClient()
+
+	phrase := args[0].String()
+	jsonTxn := args[1].String()
+	index := uint64(args[2].Int())
+	callback := args[3]
+
+	var txn types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		if _, err := wallet.AddMultisigSignature(cs, &seed, &txn, index); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		obj, err := interfaceToJSON(txn)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding signed transaction: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func mergeMultisigSignatures(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonTxns := args[0].String()
+	callback := args[1]
+
+	var txns []types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxns), &txns); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	merged, err := wallet.MergeMultisigSignatures(txns...)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(merged)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding merged transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func finalizeMultisig(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonTxn := args[0].String()
+	callback := args[1]
+
+	var txn types.V2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return err.Error()
+	}
+
+	if err := wallet.FinalizeMultisig(&txn); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(txn)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding finalized transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func createPartialTransaction(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	jsonTxn := args[0].String()
+	callback := args[1]
+
+	// the unsigned transaction returned by buildV2Transaction
+	var unsigned wallet.UnsignedV2Transaction
+	if err := json.Unmarshal([]byte(jsonTxn), &unsigned); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing transaction: %s", err), js.Null())
+		return err.Error()
+	}
+
+	p, err := wallet.NewPartialTransaction(unsigned.Basis, unsigned.Transaction, unsigned.SigningIndices)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	encoded, err := encodePartialTransaction(p)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding partial transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), encoded)
+	return nil
+}
+
+func inspectPartialTransaction(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[1].Type() != js.TypeFunction {
+		return "expected partial transaction and callback"
+	}
+
+	callback := args[1]
+
+	p, err := jsPartialTransaction(args[0])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	summary, err := wallet.InspectPartialTransaction(p)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(summary)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding summary: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func signPartialTransaction(this js.Value, args []js.Value) any {
+	if len(args) != 4 || args[0].Type() != js.TypeString || args[2].Type() != js.TypeObject || args[3].Type() != js.TypeFunction {
+		return "expected seed, partial transaction, indices and callback"
+	}
+
+	w := newClient()
+
+	phrase := args[0].String()
+	indices := jsIndices(args[2])
+	callback := args[3]
+
+	p, err := jsPartialTransaction(args[1])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	go func() {
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		if _, err := wallet.SignPartialTransaction(cs, &seed, &p, indices); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		encoded, err := encodePartialTransaction(p)
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding partial transaction: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), encoded)
+	}()
+	return nil
+}
+
+func combinePartialTransactions(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeObject, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	callback := args[1]
+
+	ps := make([]wallet.PartialTransaction, args[0].Length())
+	for i := range ps {
+		p, err := jsPartialTransaction(args[0].Index(i))
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("partial transaction %d: %s", i, err), js.Null())
+			return nil
+		}
+		ps[i] = p
+	}
+
+	combined, err := wallet.CombinePartialTransactions(ps...)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	encoded, err := encodePartialTransaction(combined)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding partial transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), encoded)
+	return nil
+}
+
+func finalizePartialTransaction(this js.Value, args []js.Value) any {
+	if len(args) != 2 || args[1].Type() != js.TypeFunction {
+		return "expected partial transaction and callback"
+	}
+
+	callback := args[1]
+
+	p, err := jsPartialTransaction(args[0])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	txn, err := wallet.FinalizePartialTransaction(p)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(txn)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func newVault(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeNumber, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	phrase := args[0].String()
+	index := uint64(args[1].Int())
+	unlockHeight := uint64(args[2].Int())
+	unlockTime := int64(args[3].Int())
+	callback := args[4]
+
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	v, err := wallet.NewVault(&seed, index, unlockHeight, unlockTime)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	obj, err := interfaceToJSON(v)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding vault: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+func findVaults(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeNumber, js.TypeNumber, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	w := newClient()
+
+	phrase := args[0].String()
+	unlockHeight := uint64(args[1].Int())
+	unlockTime := int64(args[2].Int())
+	start := uint64(args[3].Int())
+	n := args[4].Int()
+	callback := args[5]
+
+	if n < 1 || n > wallet.MaxVaultScan {
+		callback.Invoke(fmt.Sprintf("key count must be between 1 and %d", wallet.MaxVaultScan), js.Null())
+		return nil
+	}
+
+	go func() {
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		vaults, err := wallet.FindVaults(w, &seed, unlockHeight, unlockTime, start, n)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		found := make([]any, 0, len(vaults))
+		for _, v := range vaults {
+			obj, err := interfaceToJSON(v)
+			if err != nil {
+				callback.Invoke(fmt.Sprintf("error encoding vault: %s", err), js.Null())
+				return
 			}
-			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
-		case wallet.EventPayout:
-			relevant = true
-			processed.Tags = append(processed.Tags, "payout")
-			processed.SiacoinOutputs = data.SiacoinElement.SiacoinOutput.Value
+			found = append(found, obj)
 		}
-		return processed, relevant
+		callback.Invoke(js.Null(), found)
+	}()
+	return nil
+}
+
+func getVaultOutputs(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	events, err := w.TPoolEvents()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get unconfirmed transactions: %w", err)
+	w := newClient()
+
+	jsonVaults := args[0].String()
+	callback := args[1]
+
+	var vaults []wallet.Vault
+	if err := json.Unmarshal([]byte(jsonVaults), &vaults); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing vaults: %s", err), js.Null())
+		return err.Error()
 	}
 
-	var unconfirmed []processedTransaction
-	for _, event := range events {
-		processed, relevant := processEvent(event)
-		if relevant {
-			unconfirmed = append(unconfirmed, processed)
+	go func() {
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
-	}
 
-	var transactions []processedTransaction
-	seen := make(map[types.Hash256]bool)
-	batch := min(100, len(addresses))
-	for i := 0; i < len(addresses); i += batch {
-		addressBatch := addresses[i:min(i+batch, len(addresses))]
-		events, err := w.BatchAddressEvents(addressBatch, 0, 100)
+		outputs, err := wallet.VaultOutputs(w, cs, vaults)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get wallet events: %w", err)
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
-		log.Println("events for addresses", len(addressBatch), ":", len(events))
 
-		for _, event := range events {
-			if seen[event.ID] {
-				continue // skip already processed events
-			}
-			seen[event.ID] = true
-			processed, relevant := processEvent(event)
-			if !relevant {
-				continue // should never happen, but just in case
-			}
-			transactions = append(transactions, processed)
-			sort.SliceStable(transactions, func(i, j int) bool {
-				// sort by timestamp, newest first
-				return transactions[i].Timestamp.After(transactions[j].Timestamp)
-			})
-			if len(transactions) >= 100 {
-				transactions = transactions[:100]
+		unspent := make([]any, 0, len(outputs))
+		for _, o := range outputs {
+			obj, err := interfaceToJSON(o)
+			if err != nil {
+				callback.Invoke(fmt.Sprintf("error encoding output: %s", err), js.Null())
+				return
 			}
+			unspent = append(unspent, obj)
 		}
-	}
-	return append(unconfirmed, transactions...), nil
+		callback.Invoke(js.Null(), unspent)
+	}()
+	return nil
 }
 
-func getWalletBalance(w *api.Client, addresses []types.Address) (wb walletBalance, err error) {
-	batch := min(1000, len(addresses))
-	for i := 0; i < len(addresses); i += batch {
-		addressBatch := addresses[i:min(i+batch, len(addresses))]
-		balance, err := w.BatchAddressBalance(addressBatch)
+func sweepVaults(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	w := newClient()
+
+	phrase := args[0].String()
+	jsonVaults := args[1].String()
+	destinationStr := args[2].String()
+	feeRateStr := args[3].String()
+	callback := args[4]
+
+	var vaults []wallet.Vault
+	if err := json.Unmarshal([]byte(jsonVaults), &vaults); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing vaults: %s", err), js.Null())
+		return err.Error()
+	}
+	var destination types.Address
+	if err := destination.UnmarshalText([]byte(destinationStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing destination: %s", err), js.Null())
+		return err.Error()
+	}
+	var feeRate types.Currency
+	if err := feeRate.UnmarshalText([]byte(feeRateStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing fee rate: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		addresses := make([]types.Address, 0, len(vaults))
+		for _, v := range vaults {
+			addresses = append(addresses, v.Address)
+		}
+		basis, utxos, err := wallet.SpendableSiacoinElements(w, addresses)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		txn, err := wallet.SweepVaults(cs, &seed, vaults, utxos, destination, feeRate)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		obj, err := interfaceToJSON(map[string]any{
+			"basis":       basis,
+			"transaction": txn,
+		})
 		if err != nil {
-			return walletBalance{}, fmt.Errorf("failed to get wallet balance: %w", err)
+			callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
+			return
 		}
-		wb.ConfirmedSiacoinBalance = wb.ConfirmedSiacoinBalance.Add(balance.Siacoins)
-		wb.ConfirmedSiafundBalance += balance.Siafunds
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func newHTLC(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeString, js.TypeNumber, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	claimKeyStr := args[0].String()
+	refundKeyStr := args[1].String()
+	hashStr := args[2].String()
+	timeout := uint64(args[3].Int())
+	callback := args[4]
+
+	var claimKey, refundKey types.PublicKey
+	if err := claimKey.UnmarshalText([]byte(claimKeyStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing claim key: %s", err), js.Null())
+		return nil
+	} else if err := refundKey.UnmarshalText([]byte(refundKeyStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing refund key: %s", err), js.Null())
+		return nil
+	}
+	var hash types.Hash256
+	if err := hash.UnmarshalText([]byte(hashStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing hash: %s", err), js.Null())
+		return nil
 	}
-	return wb, nil
+
+	htlc, err := wallet.NewHTLC(claimKey, refundKey, hash, timeout)
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	obj, err := interfaceToJSON(htlc)
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding HTLC: %s", err), js.Null())
+		return nil
+	}
+	callback.Invoke(js.Null(), obj)
+	return nil
 }
 
-func getWalletSiacoinOutputs(w *api.Client, addresses []types.Address) ([]siacoinOutput, error) {
-	if len(addresses) == 0 {
-		return nil, nil
+func newPreimage(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	tip, err := w.ConsensusTip()
+	callback := args[0]
+	preimage, hash := wallet.NewPreimage()
+	obj, err := interfaceToJSON(map[string]any{
+		"preimage": hex.EncodeToString(preimage[:]),
+		"hash":     hash,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get consensus state: %w", err)
+		callback.Invoke(fmt.Sprintf("error encoding preimage: %s", err), js.Null())
+		return nil
 	}
+	callback.Invoke(js.Null(), obj)
+	return nil
+}
+
+// spendHTLC parses the arguments shared by claimHTLC and refundHTLC and
+// invokes the callback with the transaction returned by spend.
+func spendHTLC(args []js.Value, spend func(cs consensus.State, seed *[32]byte, index uint64, htlc wallet.HTLC, utxos []types.SiacoinElement, destination types.Address, feeRate types.Currency) (types.V2Transaction, error)) any {
+	w := newClient()
 
-	relevantAddresses := make(map[types.Address]bool, len(addresses))
-	for _, addr := range addresses {
-		relevantAddresses[addr] = true
+	phrase := args[0].String()
+	index := uint64(args[1].Int())
+	jsonHTLC := args[2].String()
+	destinationStr := args[3].String()
+	feeRateStr := args[4].String()
+	callback := args[len(args)-1]
+
+	var htlc wallet.HTLC
+	if err := json.Unmarshal([]byte(jsonHTLC), &htlc); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing HTLC: %s", err), js.Null())
+		return err.Error()
+	}
+	var destination types.Address
+	if err := destination.UnmarshalText([]byte(destinationStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing destination: %s", err), js.Null())
+		return err.Error()
+	}
+	var feeRate types.Currency
+	if err := feeRate.UnmarshalText([]byte(feeRateStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing fee rate: %s", err), js.Null())
+		return err.Error()
 	}
 
-	const addressBatchSize = 100
-	const outputPageSize = 100
+	go func() {
+		cs, err := tipState(w)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
 
-	var utxos []siacoinOutput
-	for i := 0; i < len(addresses); i += addressBatchSize {
-		addressBatch := addresses[i:min(i+addressBatchSize, len(addresses))]
-		for offset := 0; ; offset += outputPageSize {
-			sces, _, err := w.BatchAddressSiacoinOutputs(addressBatch, offset, outputPageSize)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get wallet siacoin outputs: %w", err)
-			}
-			for _, sce := range sces {
-				if sce.MaturityHeight > tip.Height {
-					continue
-				}
-				utxos = append(utxos, siacoinOutput{
-					OutputID:   sce.ID,
-					UnlockHash: sce.SiacoinOutput.Address,
-					Value:      sce.SiacoinOutput.Value,
-				})
-			}
-			if len(sces) < outputPageSize {
-				break
-			}
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
+
+		basis, utxos, err := wallet.SpendableSiacoinElements(w, []types.Address{htlc.Address})
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		txn, err := spend(cs, &seed, index, htlc, utxos, destination, feeRate)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		}
+
+		obj, err := interfaceToJSON(map[string]any{
+			"basis":       basis,
+			"transaction": txn,
+		})
+		if err != nil {
+			callback.Invoke(fmt.Sprintf("error encoding transaction: %s", err), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func claimHTLC(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeString, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	buf, err := wallet.DecodeString(args[5].String(), wallet.EncodingHex)
+	if err != nil || len(buf) != 32 {
+		args[6].Invoke("preimage must be 32 bytes", js.Null())
+		return "preimage must be 32 bytes"
+	}
+	var preimage [32]byte
+	copy(preimage[:], buf)
+
+	return spendHTLC(args, func(cs consensus.State, seed *[32]byte, index uint64, htlc wallet.HTLC, utxos []types.SiacoinElement, destination types.Address, feeRate types.Currency) (types.V2Transaction, error) {
+		return wallet.ClaimHTLC(cs, seed, index, htlc, utxos, preimage, destination, feeRate)
+	})
+}
+
+func refundHTLC(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeNumber, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
-	return utxos, nil
+	return spendHTLC(args, wallet.RefundHTLC)
 }
 
-func getWalletSiafundOutputs(w *api.Client, addresses []types.Address) ([]siafundOutput, types.Currency, error) {
-	if len(addresses) == 0 {
-		return nil, types.ZeroCurrency, nil
+func findHTLCPreimage(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
 	}
 
-	cs, err := w.ConsensusTipState()
+	w := newClient()
+
+	jsonHTLC := args[0].String()
+	callback := args[1]
+
+	var htlc wallet.HTLC
+	if err := json.Unmarshal([]byte(jsonHTLC), &htlc); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing HTLC: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		preimage, ok, err := wallet.FindHTLCPreimage(w, htlc)
+		if err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
+		} else if !ok {
+			callback.Invoke(js.Null(), js.Null())
+			return
+		}
+		callback.Invoke(js.Null(), hex.EncodeToString(preimage[:]))
+	}()
+	return nil
+}
+
+// jsMessage returns the bytes of a Uint8Array or of a UTF-8 string
+func jsMessage(v js.Value) ([]byte, error) {
+	switch {
+	case v.Type() == js.TypeString:
+		return []byte(v.String()), nil
+	case v.InstanceOf(js.Global().Get("Uint8Array")):
+		buf := make([]byte, v.Length())
+		js.CopyBytesToGo(buf, v)
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("expected Uint8Array or string, got %s", v.Type())
+	}
+}
+
+func signMessage(this js.Value, args []js.Value) any {
+	if len(args) != 4 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeNumber || args[3].Type() != js.TypeFunction {
+		return "expected seed, index, message and callback"
+	}
+
+	phrase := args[0].String()
+	index := uint64(args[1].Int())
+	callback := args[3]
+
+	message, err := jsMessage(args[2])
 	if err != nil {
-		return nil, types.ZeroCurrency, fmt.Errorf("failed to get consensus state: %w", err)
+		callback.Invoke(err.Error(), js.Null())
+		return nil
 	}
-	log.Println("tax revenue", cs.SiafundTaxRevenue)
 
-	relevantAddresses := make(map[types.Address]bool, len(addresses))
-	for _, addr := range addresses {
-		relevantAddresses[addr] = true
+	var seed [32]byte
+	defer clear(seed[:])
+	if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
 	}
 
-	const addressBatchSize = 100
-	const outputPageSize = 100
+	callback.Invoke(js.Null(), wallet.SignMessage(&seed, index, message).String())
+	return nil
+}
 
-	var claimBalance types.Currency
-	var utxos []siafundOutput
-	for i := 0; i < len(addresses); i += addressBatchSize {
-		addressBatch := addresses[i:min(i+addressBatchSize, len(addresses))]
-		for offset := 0; ; offset += outputPageSize {
-			sfes, _, err := w.BatchAddressSiafundOutputs(addressBatch, offset, outputPageSize)
-			if err != nil {
-				return nil, types.ZeroCurrency, fmt.Errorf("failed to get wallet siafund outputs: %w", err)
-			}
-			for _, sfe := range sfes {
-				dividend := cs.SiafundTaxRevenue.Sub(sfe.ClaimStart).Div64(cs.SiafundCount()).Mul64(sfe.SiafundOutput.Value)
-				log.Println("siafund", sfe.ID, sfe.ClaimStart, dividend)
-				claimBalance = claimBalance.Add(dividend)
-				utxos = append(utxos, siafundOutput{
-					OutputID:   sfe.ID,
-					UnlockHash: sfe.SiafundOutput.Address,
-					Value:      sfe.SiafundOutput.Value,
-				})
-			}
-			if len(sfes) < outputPageSize {
-				break
-			}
+func verifyMessage(this js.Value, args []js.Value) any {
+	if len(args) != 4 || args[0].Type() != js.TypeString || args[2].Type() != js.TypeString || args[3].Type() != js.TypeFunction {
+		return "expected address or unlock conditions, message, signature and callback"
+	}
+
+	signer := strings.TrimSpace(args[0].String())
+	signatureStr := args[2].String()
+	callback := args[3]
+
+	message, err := jsMessage(args[1])
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+	var ms wallet.MessageSignature
+	if err := ms.UnmarshalText([]byte(signatureStr)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing signature: %s", err), js.Null())
+		return nil
+	}
+
+	// the signer is either an address or JSON encoded unlock conditions
+	var addr types.Address
+	if strings.HasPrefix(signer, "{") {
+		var uc types.UnlockConditions
+		if err := json.Unmarshal([]byte(signer), &uc); err != nil {
+			callback.Invoke(fmt.Sprintf("error parsing unlock conditions: %s", err), js.Null())
+			return nil
 		}
+		addr, err = wallet.VerifyMessageUnlockConditions(uc, message, ms)
+	} else if err = addr.UnmarshalText([]byte(signer)); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing address: %s", err), js.Null())
+		return nil
+	} else {
+		err = wallet.VerifyMessage(addr, message, ms)
+	}
+	if err != nil {
+		callback.Invoke(err.Error(), js.Null())
+		return nil
+	}
+
+	obj, err := interfaceToJSON(map[string]any{
+		"address":    addr,
+		"public_key": ms.PublicKey,
+	})
+	if err != nil {
+		callback.Invoke(fmt.Sprintf("error encoding result: %s", err), js.Null())
+		return nil
 	}
-	return utxos, claimBalance, nil
+	callback.Invoke(js.Null(), obj)
+	return nil
 }
 
-func getTransactions(this js.Value, args []js.Value) any {
-	if err := checkArgs(args, js.TypeObject, js.TypeFunction); err != nil {
+func proveReserves(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
 		return err.Error()
 	}
 
-	count := args[0].Length()
-	callback := args[1]
+	w := newClient()
 
-	go func() {
-		w := api.NewClient(SIASCAN_ADDRESS, "")
+	phrase := args[0].String()
+	jsonAddresses := args[1].String()
+	challenge := args[2].String()
+	callback := args[3]
 
-		var addresses []types.Address
-		for i := range count {
-			var addr types.Address
-			if err := addr.UnmarshalText([]byte(args[0].Index(i).String())); err != nil {
-				callback.Invoke(fmt.Sprintf("error parsing address %d: %s", i, err), js.Null())
-				return
-			}
-			addresses = append(addresses, addr)
+	var addresses []wallet.Address
+	if err := json.Unmarshal([]byte(jsonAddresses), &addresses); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing addresses: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		var seed [32]byte
+		defer clear(seed[:])
+		if err := wallet.PhraseToSeed(phrase, &seed); err != nil {
+			callback.Invoke(err.Error(), js.Null())
+			return
 		}
-		walletResp, err := getWalletBalance(w, addresses)
+
+		p, err := wallet.ProveReserves(w, &seed, addresses, challenge)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting wallet balance: %s", err), js.Null())
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
-		walletResp.Transactions, err = getWalletTransactions(w, addresses)
+		obj, err := interfaceToJSON(p)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting wallet transactions: %s", err), js.Null())
+			callback.Invoke(fmt.Sprintf("error encoding proof: %s", err), js.Null())
 			return
 		}
+		callback.Invoke(js.Null(), obj)
+	}()
+	return nil
+}
+
+func verifyReserveProof(this js.Value, args []js.Value) any {
+	if err := checkArgs(args, js.TypeString, js.TypeString, js.TypeFunction); err != nil {
+		return err.Error()
+	}
+
+	w := newClient()
+
+	jsonProof := args[0].String()
+	challenge := args[1].String()
+	callback := args[2]
 
-		walletResp.UnspentSiacoinOutputs, err = getWalletSiacoinOutputs(w, addresses)
+	var p wallet.ReserveProof
+	if err := json.Unmarshal([]byte(jsonProof), &p); err != nil {
+		callback.Invoke(fmt.Sprintf("error parsing proof: %s", err), js.Null())
+		return err.Error()
+	}
+
+	go func() {
+		// the proof is checked against the state at its pinned index, not
+		// the current tip
+		resp, err := w.ConsensusCheckpointID(p.Index.ID)
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting wallet siacoin outputs: %s", err), js.Null())
+			callback.Invoke(fmt.Sprintf("error getting consensus state at %v: %s", p.Index, err), js.Null())
+			return
+		} else if err := currentNetwork().CheckNetwork(resp.State.Network); err != nil {
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
 
-		walletResp.UnspentSiafundOutputs, walletResp.SiafundClaim, err = getWalletSiafundOutputs(w, addresses)
-		if err != nil {
-			callback.Invoke(fmt.Sprintf("error getting wallet siafund outputs: %s", err), js.Null())
+		if err := wallet.VerifyReserveProof(resp.State, challenge, p); err != nil {
+			callback.Invoke(err.Error(), js.Null())
 			return
 		}
-
-		obj, err := interfaceToJSON(walletResp)
+		obj, err := interfaceToJSON(map[string]any{
+			"index":    p.Index,
+			"siacoins": p.Siacoins,
+			"siafunds": p.Siafunds,
+		})
 		if err != nil {
-			callback.Invoke(fmt.Sprintf("error encoding wallet response: %s", err), js.Null())
+			callback.Invoke(fmt.Sprintf("error encoding result: %s", err), js.Null())
 			return
 		}
 		callback.Invoke(js.Null(), obj)
 	}()
-
 	return nil
 }